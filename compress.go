@@ -0,0 +1,175 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// gzipMagic starts every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// multiCloser closes an input stream along with the file under it.
+type multiCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m multiCloser) Close() error {
+	var err error
+	for _, c := range m.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// openInput opens the source of j, decompressing gzip data and zip
+// members. It returns the uncompressed size for the progress bar, 0 when
+// it is not known.
+func openInput(j job) (io.ReadCloser, int64, error) {
+	if j.member != "" {
+		return openMember(j.in, j.member)
+	}
+	f := os.Stdin
+	if j.in != "-" {
+		var err error
+		if f, err = os.Open(j.in); err != nil {
+			return nil, 0, fmt.Errorf("cannot open source file: %v", err)
+		}
+	}
+	size := fileSize(f)
+
+	// Regular files are checked in place so the gzip size trailer can be
+	// read; anything else is sniffed from a buffer.
+	magic := make([]byte, 2)
+	if _, err := f.ReadAt(magic, 0); err == nil {
+		if !strings.HasPrefix(string(magic), string(gzipMagic)) {
+			return f, size, nil
+		}
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, 0, fmt.Errorf("reading gzip source file: %v", err)
+		}
+		return multiCloser{gr, []io.Closer{gr, f}}, gzipSize(f, size), nil
+	}
+	br := bufio.NewReader(f)
+	if b, _ := br.Peek(2); string(b) != string(gzipMagic) {
+		return multiCloser{br, []io.Closer{f}}, size, nil
+	}
+	gr, err := gzip.NewReader(br)
+	if err != nil {
+		f.Close()
+		return nil, 0, fmt.Errorf("reading gzip source file: %v", err)
+	}
+	return multiCloser{gr, []io.Closer{gr, f}}, 0, nil
+}
+
+// gzipSize returns the uncompressed size recorded in the trailer of a gzip
+// file of size bytes. It is exact for single stream files under 4GiB.
+func gzipSize(f *os.File, size int64) int64 {
+	b := make([]byte, 4)
+	if size < 4 {
+		return 0
+	}
+	if _, err := f.ReadAt(b, size-4); err != nil {
+		return 0
+	}
+	return int64(binary.LittleEndian.Uint32(b))
+}
+
+// openMember opens one file inside a zip archive.
+func openMember(archive, member string) (io.ReadCloser, int64, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot open source archive: %v", err)
+	}
+	for _, f := range zr.File {
+		if f.Name != member {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			zr.Close()
+			return nil, 0, fmt.Errorf("cannot open %v in %v: %v", member, archive, err)
+		}
+		return multiCloser{rc, []io.Closer{rc, zr}}, int64(f.UncompressedSize64), nil
+	}
+	zr.Close()
+	return nil, 0, fmt.Errorf("%v not found in %v", member, archive)
+}
+
+// zipMembers lists the input files inside a zip archive, skipping
+// directories, previous outputs and anything that is not an input type.
+func zipMembers(archive string) ([]string, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open source archive: %v", err)
+	}
+	defer zr.Close()
+	var m []string
+	for _, f := range zr.File {
+		name := path.Base(f.Name)
+		if f.FileInfo().IsDir() || strings.HasPrefix(name, ".") || !isInput(name) || isOutput(name) {
+			continue
+		}
+		m = append(m, f.Name)
+	}
+	if len(m) == 0 {
+		return nil, fmt.Errorf("%v does not contain an input file", archive)
+	}
+	return m, nil
+}
+
+// stripGz removes a trailing .gz from name.
+func stripGz(name string) string {
+	if strings.EqualFold(path.Ext(name), ".gz") {
+		return name[:len(name)-3]
+	}
+	return name
+}
+
+// gzipFile is an output file written through gzip.
+type gzipFile struct {
+	*gzip.Writer
+	f io.Closer
+}
+
+func (g gzipFile) Close() error {
+	if err := g.Writer.Close(); err != nil {
+		g.f.Close()
+		return err
+	}
+	return g.f.Close()
+}
+
+// nopCloser keeps stdout open when an output is closed.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// createOutput creates the output file path, "-" for stdout, compressing
+// what is written to it when gz is set.
+func createOutput(path string, gz bool) (io.WriteCloser, error) {
+	var f io.WriteCloser = nopCloser{os.Stdout}
+	if path != "-" {
+		var err error
+		if f, err = os.Create(path); err != nil {
+			return nil, err
+		}
+	}
+	if gz {
+		return gzipFile{gzip.NewWriter(f), f}, nil
+	}
+	return f, nil
+}
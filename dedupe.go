@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/rssenar/monju/record"
+)
+
+// hold collects every record so fn can work across the whole file, as
+// deduplication and householding do, then passes them on in the order
+// received. fn has returned before the returned channel is closed.
+func hold(in <-chan record.Record, fn func(recs []record.Record)) <-chan record.Record {
+	out := make(chan record.Record)
+	go func() {
+		defer close(out)
+		var recs []record.Record
+		for r := range in {
+			recs = append(recs, r)
+		}
+		fn(recs)
+		for _, r := range recs {
+			out <- r
+		}
+	}()
+	return out
+}
+
+// writeDuplicates lists each dropped duplicate with the record kept in its
+// place. When there are none, a report left by an earlier run is removed.
+func writeDuplicates(path string, dups []record.Duplicate) error {
+	if len(dups) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("cannot remove old duplicates report: %v", err)
+		}
+		return nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("cannot create duplicates report: %v", err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write([]string{"customerid", "row", "kept_customerid", "kept_row", "key"})
+	for _, d := range dups {
+		w.Write([]string{d.Dropped.Get("customerid"), strconv.Itoa(d.Dropped.Counter),
+			d.Kept.Get("customerid"), strconv.Itoa(d.Kept.Counter), d.Key})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	fmt.Fprintf(status, "Dropped %v duplicate records, see %v\n", len(dups), path)
+	return f.Close()
+}
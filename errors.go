@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// rowErrors logs malformed source rows with their line numbers. The log
+// file is only created once the first bad row is seen.
+type rowErrors struct {
+	path string
+	f    *os.File
+	n    int
+}
+
+func (e *rowErrors) add(pe error, row []string) error {
+	if e.f == nil {
+		f, err := os.Create(e.path)
+		if err != nil {
+			return fmt.Errorf("cannot create error file: %v", err)
+		}
+		e.f = f
+	}
+	e.n++
+	var err error
+	if len(row) > 0 {
+		_, err = fmt.Fprintf(e.f, "%v: %v\n", pe, strings.Join(row, ","))
+	} else {
+		_, err = fmt.Fprintln(e.f, pe)
+	}
+	return err
+}
+
+func (e *rowErrors) close() error {
+	if e.f == nil {
+		return nil
+	}
+	if err := e.f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(status, "Skipped %v malformed rows, see %v\n", e.n, e.path)
+	return nil
+}
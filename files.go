@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// outputSuffixes end the names of the files monju writes, ahead of the
+// format extension; matching files are never picked up again as input.
+var outputSuffixes = []string{"_output", "_suppressed", "_rejects", "_duplicates"}
+
+// job describes one input file and where its results go.
+type job struct {
+	in     string // source path, "-" for stdin
+	member string // file inside the in zip archive, if any
+	out    string // output path, "-" for stdout
+	base   string // prefix for the _suppressed, _rejects, _duplicates and _errors side files
+	format string // output format, a key of formats
+	gzip   bool   // compress the output files
+}
+
+// file returns the name of the side file with suffix, in the job's format.
+func (j job) file(suffix string) string {
+	name := j.base + suffix + formats[j.format]
+	if j.gzip {
+		name += ".gz"
+	}
+	return name
+}
+
+// name identifies the source of j in messages.
+func (j job) name() string {
+	if j.member != "" {
+		return j.in + ":" + j.member
+	}
+	return j.in
+}
+
+// source returns the name of the data read for j without any .gz, which
+// decides how it is parsed.
+func (j job) source() string {
+	if j.member != "" {
+		return stripGz(j.member)
+	}
+	return stripGz(j.in)
+}
+
+// isOutput reports whether name looks like a file written by a previous run.
+func isOutput(name string) bool {
+	name = stripGz(name)
+	name = strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))
+	for _, s := range outputSuffixes {
+		if strings.HasSuffix(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// inputs expands the command line arguments into input paths. Globs skip
+// previous outputs, literal paths are used as given and "-" means stdin.
+// With no arguments every input file and zip archive in the current
+// directory is used.
+func inputs(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return readDir()
+	}
+	var f []string
+	for _, a := range args {
+		if a == "-" {
+			f = append(f, a)
+			continue
+		}
+		if !strings.ContainsAny(a, "*?[") {
+			if _, err := os.Stat(a); err != nil {
+				return nil, fmt.Errorf("cannot open input file: %v", err)
+			}
+			f = append(f, a)
+			continue
+		}
+		m, err := filepath.Glob(a)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %v: %v", a, err)
+		}
+		for _, v := range m {
+			if !isOutput(v) {
+				f = append(f, v)
+			}
+		}
+	}
+	if len(f) < 1 {
+		return nil, fmt.Errorf("no input files matched %v", strings.Join(args, " "))
+	}
+	return f, nil
+}
+
+// jobs pairs each input with its output file in format, compressed when
+// gz is set. Zip archives give one job per input file inside them, named
+// after that file with its directories joined by "_". out names the output
+// of a single input ("-" for stdout); otherwise outputs are written next
+// to the input, or into outdir when it is set. Inputs that would write the
+// same output files, such as x.csv and x.csv.gz, are refused.
+func jobs(in []string, out, outdir, format string, gz bool) ([]job, error) {
+	var src []job
+	for _, v := range in {
+		if !isArchive(v) {
+			src = append(src, job{in: v})
+			continue
+		}
+		m, err := zipMembers(v)
+		if err != nil {
+			return nil, err
+		}
+		for _, mb := range m {
+			src = append(src, job{in: v, member: mb})
+		}
+	}
+	if out != "" && len(src) > 1 {
+		return nil, errors.New("-o needs exactly one input file, use -outdir for several")
+	}
+	var j []job
+	for _, jb := range src {
+		name := "stdin"
+		switch {
+		case jb.member != "":
+			name = filepath.Join(filepath.Dir(jb.in), strings.Replace(path.Clean(jb.source()), "/", "_", -1))
+		case jb.in != "-":
+			name = jb.source()
+		}
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+		if outdir != "" {
+			name = filepath.Join(outdir, filepath.Base(name))
+		}
+		jb.base, jb.format, jb.gzip = name, format, gz
+		jb.out = jb.file("_output")
+		if out != "" {
+			jb.out = out
+			if out != "-" {
+				o := stripGz(out)
+				jb.base = strings.TrimSuffix(strings.TrimSuffix(o, filepath.Ext(o)), "_output")
+			}
+		}
+		j = append(j, jb)
+	}
+	seen := make(map[string]string)
+	for _, jb := range j {
+		if prev, ok := seen[jb.base]; ok {
+			return nil, fmt.Errorf("%v and %v would both write %v", prev, jb.name(), jb.out)
+		}
+		seen[jb.base] = jb.name()
+	}
+	return j, nil
+}
+
+// removeOutputs deletes the incomplete output files of a failed job. The
+// _errors.log file is kept to help diagnose the failure.
+func removeOutputs(j job) {
+	files := []string{
+		j.file("_suppressed"),
+		j.file("_rejects"),
+		j.base + "_duplicates.csv",
+	}
+	if j.out != "-" {
+		files = append(files, j.out)
+	} else {
+		log.Println("Output written to stdout is incomplete")
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			log.Println("Cannot remove incomplete output", err)
+		}
+	}
+}
+
+// quarantine moves a failed input file into a quarantine directory next to
+// it so it is not picked up again by the next run. A zip archive is moved
+// as a whole.
+func quarantine(in string) error {
+	if in == "-" {
+		return nil
+	}
+	dir := filepath.Join(filepath.Dir(in), "quarantine")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create quarantine directory: %v", err)
+	}
+	if err := os.Rename(in, filepath.Join(dir, filepath.Base(in))); err != nil {
+		return fmt.Errorf("cannot quarantine %v: %v", in, err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// layoutField places one output column in a fixed-width record.
+type layoutField struct {
+	Field string // output field key, or source name of an appended passthrough column
+	Start int    // 1-based byte position of the first character
+	Width int    // in bytes of UTF-8
+	Align string // left (default) or right
+	Pad   string // single byte fill character, a space when empty
+}
+
+// layout describes a fixed-width record.
+type layout []layoutField
+
+// loadLayout reads a fixed-width layout file and checks that its fields
+// are well formed and do not overlap.
+func loadLayout(path string) (layout, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open layout file: %v", err)
+	}
+	defer f.Close()
+
+	var l layout
+	if err := json.NewDecoder(f).Decode(&l); err != nil {
+		return nil, fmt.Errorf("decoding layout file %v: %v", path, err)
+	}
+	if len(l) == 0 {
+		return nil, fmt.Errorf("layout file %v has no fields", path)
+	}
+	var bad []string
+	for i, v := range l {
+		switch {
+		case v.Start < 1 || v.Width < 1:
+			bad = append(bad, fmt.Sprintf("%v needs a Start and Width of at least 1", v.Field))
+		case v.Align != "" && v.Align != "left" && v.Align != "right":
+			bad = append(bad, fmt.Sprintf("%v has Align %q, use left or right", v.Field, v.Align))
+		case len(v.Pad) > 1:
+			bad = append(bad, fmt.Sprintf("%v has Pad %q, use a single byte character", v.Field, v.Pad))
+		}
+		if v.Pad == "" {
+			l[i].Pad = " "
+		}
+	}
+	s := append(layout{}, l...)
+	sort.Slice(s, func(i, j int) bool { return s[i].Start < s[j].Start })
+	for i := 1; i < len(s); i++ {
+		if p := s[i-1]; p.Start+p.Width > s[i].Start {
+			bad = append(bad, fmt.Sprintf("%v overlaps %v", s[i].Field, p.Field))
+		}
+	}
+	if len(bad) > 0 {
+		return nil, fmt.Errorf("invalid layout file %v: %v", path, strings.Join(bad, "; "))
+	}
+	return l, nil
+}
+
+// truncation is a value cut to fit its fixed-width field.
+type truncation struct {
+	row   int // source row
+	field string
+	value string // the value before it was cut
+}
+
+// fixedWriter writes records in a fixed-width layout, truncating values
+// that are wider than their field and keeping a list of them. Widths are
+// in bytes of UTF-8, so values are cut on a character boundary and the
+// rest of the field is padded.
+type fixedWriter struct {
+	w         *bufio.Writer
+	lay       layout
+	col       []int // output column of each layout field, -1 when absent
+	size      int
+	row       int // source row of the next Write, for truncations
+	truncated []truncation
+}
+
+// newFixedWriter lines the layout up with the output columns named by keys.
+func newFixedWriter(w io.Writer, lay layout, keys []string) (*fixedWriter, error) {
+	col, err := lay.columns(keys)
+	if err != nil {
+		return nil, err
+	}
+	fw := &fixedWriter{w: bufio.NewWriter(w), lay: lay, col: col}
+	for _, v := range lay {
+		if end := v.Start + v.Width - 1; end > fw.size {
+			fw.size = end
+		}
+	}
+	return fw, nil
+}
+
+// columns returns the position in keys of each layout field, matched
+// case-insensitively, or -1 for reason when keys lack it. Every other
+// layout field must be one of keys, since reason is only carried by the
+// side files.
+func (l layout) columns(keys []string) ([]int, error) {
+	var col []int
+	var missing []string
+	for _, v := range l {
+		c := -1
+		for i, k := range keys {
+			if strings.EqualFold(k, v.Field) {
+				c = i
+				break
+			}
+		}
+		if c < 0 && !strings.EqualFold(v.Field, "reason") {
+			missing = append(missing, fmt.Sprintf("%q", v.Field))
+		}
+		col = append(col, c)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("layout fields not in the output: %v", strings.Join(missing, ", "))
+	}
+	return col, nil
+}
+
+func (fw *fixedWriter) Write(row []string) error {
+	rec := bytes.Repeat([]byte{' '}, fw.size)
+	for i, v := range fw.lay {
+		var val string
+		if c := fw.col[i]; c >= 0 && c < len(row) {
+			val = row[c]
+		}
+		if len(val) > v.Width {
+			fw.truncated = append(fw.truncated, truncation{fw.row, v.Field, val})
+			n := v.Width
+			for n > 0 && !utf8.RuneStart(val[n]) {
+				n--
+			}
+			val = val[:n]
+		}
+		pad := strings.Repeat(v.Pad, v.Width-len(val))
+		cell := val + pad
+		if v.Align == "right" {
+			cell = pad + val
+		}
+		copy(rec[v.Start-1:], cell)
+	}
+	rec = append(rec, '\n')
+	_, err := fw.w.Write(rec)
+	return err
+}
+
+func (fw *fixedWriter) Flush() error {
+	return fw.w.Flush()
+}
@@ -0,0 +1,92 @@
+// Package geo holds the ZIP code, state and distance helpers used to place
+// mail records.
+package geo
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+func hsin(theta float64) float64 {
+	// haversin(θ) function
+	return math.Pow(math.Sin(theta/2), 2)
+}
+
+// Distance returns the great-circle distance in miles between two points
+// given in decimal degrees.
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	// convert to radians, must cast radius as float to multiply later
+	var la1, lo1, la2, lo2, rad float64
+	la1 = lat1 * math.Pi / 180
+	lo1 = lon1 * math.Pi / 180
+	la2 = lat2 * math.Pi / 180
+	lo2 = lon2 * math.Pi / 180
+	rad = 3959 // Earth radius in Miles
+	// calculate
+	h := hsin(la2-la1) + math.Cos(la1)*math.Cos(la2)*hsin(lo2-lo1)
+	return 2 * rad * math.Asin(math.Sqrt(h))
+}
+
+// ValZip standardizes a ZIP or ZIP+4 code to the 4 or 5 digit form used
+// as the key in USZIPCoordinates.csv. It returns "" for invalid codes.
+func ValZip(p string) string {
+	switch {
+	case regexp.MustCompile(`^[0-9][0-9][0-9][0-9]$`).MatchString(p):
+		return p
+	case regexp.MustCompile(`^[0-9][0-9][0-9][0-9][0-9]$`).MatchString(p):
+		if p[:1] == "0" {
+			p = p[1:]
+			return p
+		}
+		return p
+	case regexp.MustCompile(`^[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]$`).MatchString(p):
+		return p[:5]
+	case regexp.MustCompile(`^[0-9][0-9][0-9][0-9][0-9]-[0-9][0-9][0-9][0-9]$`).MatchString(p):
+		x := strings.Split(p, "-")
+		return x[0]
+	}
+	return ""
+}
+
+// SCF returns the Sectional Center Facility prefix of a standardized ZIP.
+func SCF(s string) string {
+	switch len(s) {
+	case 5:
+		if s[:1] != "0" {
+			return s[:3]
+		}
+		return s[1:3]
+	case 4:
+		return s[:2]
+	}
+	return ""
+}
+
+// StateName expands a 2-digit US state abbreviation, returning s unchanged
+// when it is not recognized.
+func StateName(s string) string {
+	// UsStatesDict is a map of 2-Digit abbreviated US States
+	usStDict := map[string]string{"AK": "Alaska", "AL": "Alabama",
+		"AR": "Arkansas", "AS": "American Samoa", "AZ": "Arizona",
+		"CA": "California", "CO": "Colorado", "CT": "Connecticut",
+		"DC": "District of Columbia", "DE": "Delaware", "FL": "Florida",
+		"GA": "Georgia", "GU": "Guam", "HI": "Hawaii", "IA": "Iowa",
+		"ID": "Idaho", "IL": "Illinois", "IN": "Indiana", "KS": "Kansas",
+		"KY": "Kentucky", "LA": "Louisiana", "MA": "Massachusetts",
+		"MD": "Maryland", "ME": "Maine", "MI": "Michigan", "MN": "Minnesota",
+		"MO": "Missouri", "MP": "Northern Mariana Islands", "MS": "Mississippi",
+		"MT": "Montana", "NA": "National", "NC": "North Carolina",
+		"ND": "North Dakota", "NE": "Nebraska", "NH": "New Hampshire",
+		"NJ": "New Jersey", "NM": "New Mexico", "NV": "Nevada", "NY": "New York",
+		"OH": "Ohio", "OK": "Oklahoma", "OR": "Oregon", "PA": "Pennsylvania",
+		"PR": "Puerto Rico", "RI": "Rhode Island", "SC": "South Carolina",
+		"SD": "South Dakota", "TN": "Tennessee", "TX": "Texas", "UT": "Utah",
+		"VA": "Virginia", "VI": "Virgin Islands", "VT": "Vermont",
+		"WA": "Washington", "WI": "Wisconsin", "WV": "West Virginia",
+		"WY": "Wyoming"}
+	if ds, ok := usStDict[s]; ok {
+		return ds
+	}
+	return s
+}
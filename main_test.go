@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rssenar/monju/record"
+)
+
+func TestReorder(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []int
+	}{
+		{"in order", []int{1, 2, 3, 4}},
+		{"reversed", []int{4, 3, 2, 1}},
+		{"shuffled", []int{3, 1, 4, 2, 6, 5}},
+		{"empty", nil},
+	}
+	for _, tt := range tests {
+		in := make(chan record.Record)
+		go func(n []int) {
+			defer close(in)
+			for _, c := range n {
+				in <- record.Record{Counter: c}
+			}
+		}(tt.in)
+		var got []int
+		for r := range reorder(in, 1) {
+			got = append(got, r.Counter)
+		}
+		if len(got) != len(tt.in) {
+			t.Fatalf("%s: got %d records, want %d", tt.name, len(got), len(tt.in))
+		}
+		for i, c := range got {
+			if c != i+1 {
+				t.Errorf("%s: got %v, want counters in order from 1", tt.name, got)
+				break
+			}
+		}
+	}
+}
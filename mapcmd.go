@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/rssenar/monju/record"
+)
+
+// mapCmd implements "monju map --suggest file", printing a draft -map file
+// for the header row of file built from the column aliases and, with
+// -sniff, from the content of its first rows.
+func mapCmd(args []string) {
+	fs := flag.NewFlagSet("map", flag.ExitOnError)
+	suggest := fs.Bool("suggest", false, "Print a draft mapping for the input file's header row")
+	sniff := fs.Bool("sniff", false, "Also detect columns from the content of the first rows")
+	noHeader := fs.Bool("noheader", false, "The file has no header row (implies -sniff)")
+	sheet := fs.String("sheet", "", "Worksheet to read from an .xlsx file (default the first)")
+	rescDir := fs.String("resources", "", "Resource directory (default $MONJU_RESOURCES or XDG data dirs)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %v map --suggest [flags] file\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if !*suggest || fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	status = os.Stderr
+	rescPath, err := findResources(*rescDir)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	proc, err := loadProcessor(rescPath, "")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	jb, err := jobs([]string{fs.Arg(0)}, "", "", "csv", false)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(jb) > 1 {
+		var m []string
+		for _, j := range jb {
+			m = append(m, j.member)
+		}
+		log.Fatalf("%v holds %v input files (%v), extract the one to map", fs.Arg(0), len(jb), strings.Join(m, ", "))
+	}
+	j := jb[0]
+	f, _, err := openInput(j)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer f.Close()
+	rdr, err := newReader(f, j.source(), runOpts{sheet: *sheet})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if c, ok := rdr.(io.Closer); ok {
+		defer c.Close()
+	}
+	var header []string
+	if !*noHeader {
+		if header, err = rdr.Read(); err != nil {
+			log.Fatalln("Cannot read header row", err)
+		}
+	}
+	var sample [][]string
+	for (*sniff || *noHeader) && len(sample) < record.SniffRows {
+		row, err := rdr.Read()
+		if err == io.EOF {
+			break
+		}
+		if _, ok := err.(*csv.ParseError); ok {
+			continue
+		}
+		if err != nil {
+			log.Fatalln("Cannot read source row", err)
+		}
+		sample = append(sample, row)
+	}
+	if *noHeader {
+		if len(sample) == 0 {
+			log.Fatalln("Cannot read first row of", j.name())
+		}
+		header = record.NumberedHeader(len(sample[0]))
+	}
+	s, g := proc.Suggest(header, sample)
+	printGuesses(header, g)
+
+	// Written by hand to keep the source column order
+	fmt.Println("{")
+	for i, kv := range s {
+		k, _ := json.Marshal(kv[0])
+		v, _ := json.Marshal(kv[1])
+		sep := ","
+		if i == len(s)-1 {
+			sep = ""
+		}
+		fmt.Printf("  %s: %s%v\n", k, v, sep)
+	}
+	fmt.Println("}")
+}
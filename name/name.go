@@ -0,0 +1,132 @@
+// Package name parses free-form personal names.
+package name
+
+import (
+	"fmt"
+	"strings"
+)
+
+func checkSalut(f string) bool {
+	salutations := []string{"MR", "MR.", "MS", "MS.", "MRS", "MRS.", "DR",
+		"DR.", "MISS", "CORP", "SGT", "PVT", "CAPT", "COL", "MAJ", "LT",
+		"LIEUTENANT", "PRM", "PATROLMAN", "HON", "OFFICER", "REV", "PRES",
+		"PRESIDENT", "GOV", "GOVERNOR", "VICE PRESIDENT", "VP", "MAYOR",
+		"SIR", "MADAM", "HONORABLE"}
+	for _, salu := range salutations {
+		if tCase(f) == tCase(salu) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkSep(f string) bool {
+	separators := []string{"&", "AND", "OR", "/"}
+	for _, sep := range separators {
+		if tCase(f) == tCase(sep) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkSuf(f string) bool {
+	suffixes := []string{"ESQ", "PHD", "MD", "TRUE"}
+	for _, suf := range suffixes {
+		if tCase(f) == tCase(suf) {
+			return true
+		}
+	}
+	return false
+}
+
+func checklnPref(f string) bool {
+	lnPrefixes := []string{"DE", "DA", "DI", "LA", "LOS", "DU", "DEL",
+		"DEI", "VDA", "DELLO", "DELLA", "DEGLI", "DELLE", "VAN", "VON",
+		"DER", "DEN", "MC", "HEER", "TEN", "TER", "VANDE", "VANDEN",
+		"VANDER", "VOOR", "VER", "AAN", "MC", "SAN", "SAINZ", "BIN", "LI",
+		"LE", "DES", "AM", "AUS'M", "VOM", "ZUM", "ZUR", "TEN", "IBN",
+		"ABUa", "BON", "BIN", "DAL", "DER", "IBN", "LE", "ST", "STE", "VAN",
+		"VEL", "VON"}
+	for _, pref := range lnPrefixes {
+		if tCase(f) == tCase(pref) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkGener(f string) bool {
+	generations := []string{"JR", "SR", "I", "II", "III", "IV", "V", "VI",
+		"VII", "VIII", "IX", "X", "1ST", "2ND", "3RD", "4TH", "5TH", "6TH",
+		"7TH", "8TH", "9TH", "10TH", "FIRST", "SECOND", "THIRD", "FOURTH",
+		"FIFTH", "SIXTH", "SEVENTH", "EIGHTH", "NINTH", "TENTH"}
+	for _, gen := range generations {
+		if tCase(f) == tCase(gen) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkNameSeparators(sl []string) []string {
+	var nsl []string
+	var gen string
+	for i := 1; i <= len(sl); i++ {
+		v := tCase(sl[len(sl)-i])
+		if checkSep(v) {
+			if i < 3 {
+				continue
+			} else {
+				break
+			}
+		} else if checkSuf(v) {
+			continue
+		} else if checkSalut(v) {
+			continue
+		} else if checkGener(v) {
+			if i == 1 {
+				gen = v
+			}
+			continue
+		} else if checklnPref(v) {
+			for _, s := range nsl {
+				v = fmt.Sprintf("%v %v", v, s)
+			}
+			nsl = []string{}
+		}
+		nsl = append([]string{v}, nsl...)
+	}
+	if gen != "" {
+		nsl[len(nsl)-1] = fmt.Sprintf("%v %v", nsl[len(nsl)-1], gen)
+		return nsl
+	}
+	return nsl
+}
+
+// Parse splits a full name into first name, middle initial and last name,
+// dropping salutations, suffixes and separators and keeping last name
+// prefixes and generations with the last name.
+func Parse(fn string) (string, string, string) {
+	fnSplit := checkNameSeparators(strings.Fields(fn))
+	switch len(fnSplit) {
+	case 1:
+		return fnSplit[0], "", ""
+	case 2:
+		return fnSplit[0], "", fnSplit[1]
+	case 3:
+		if len(fnSplit[2]) == 1 && len(fnSplit[1]) > 2 {
+			return fnSplit[0], "", fnSplit[1]
+		}
+		return fnSplit[0], fnSplit[1], fnSplit[2]
+	case 4:
+		return fnSplit[0], "", fnSplit[3]
+	case 5:
+		return fnSplit[0], "", fnSplit[4]
+	}
+	return "", "", ""
+}
+
+func tCase(f string) string {
+	return strings.TrimSpace(strings.Title(strings.ToLower(f)))
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// inputExts are the file extensions picked up as input.
+var inputExts = []string{".csv", ".txt", ".tsv", ".psv", ".xlsx"}
+
+// isInput reports whether name has one of the inputExts, possibly gzipped.
+func isInput(name string) bool {
+	ext := strings.ToLower(filepath.Ext(stripGz(name)))
+	for _, e := range inputExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// isArchive reports whether name is a zip archive of input files.
+func isArchive(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".zip")
+}
+
+// rowReader yields the rows of an input file one at a time. Rows with the
+// wrong number of fields are reported as *csv.ParseError.
+type rowReader interface {
+	Read() ([]string, error)
+}
+
+// newReader returns a rowReader for the input named name, chosen by its
+// extension. o selects the worksheet of an .xlsx file and overrides what
+// is detected about delimited text.
+func newReader(r io.Reader, name string, o runOpts) (rowReader, error) {
+	if strings.EqualFold(filepath.Ext(name), ".xlsx") {
+		return newXLSXReader(r, o.sheet)
+	}
+	return newTextReader(r, o)
+}
+
+// sniffSize is how much of a delimited input is examined to detect its
+// encoding, delimiter and quoting.
+const sniffSize = 64 << 10
+
+// encodings are the text encodings accepted by -encoding.
+var encodings = map[string]encoding.Encoding{
+	"utf8":   nil,
+	"utf16":  unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+	"cp1252": charmap.Windows1252,
+	"latin1": charmap.ISO8859_1,
+}
+
+// delims are the delimiters tried on delimited input, with their names.
+var delims = []struct {
+	name  string
+	comma rune
+}{{"comma", ','}, {"tab", '\t'}, {"pipe", '|'}, {"semicolon", ';'}}
+
+// parseDelim turns a -delim value, a delimiter name or a single character,
+// into the delimiter rune; "" leaves it to detection.
+func parseDelim(s string) (rune, error) {
+	if s == "" {
+		return 0, nil
+	}
+	for _, d := range delims {
+		if s == d.name {
+			return d.comma, nil
+		}
+	}
+	if utf8.RuneCountInString(s) == 1 {
+		c, _ := utf8.DecodeRuneInString(s)
+		return c, nil
+	}
+	return 0, fmt.Errorf("invalid delimiter %q, use comma, tab, pipe, semicolon or a single character", s)
+}
+
+// newTextReader reads delimited text. Unless o says otherwise it strips a
+// byte order mark, decodes UTF-16 and non UTF-8 text (taken as CP1252) to
+// UTF-8, picks the delimiter that splits the first lines most consistently
+// and allows lazy quotes when strict quoting fails on them.
+func newTextReader(r io.Reader, o runOpts) (rowReader, error) {
+	br := bufio.NewReaderSize(r, sniffSize)
+	head, err := br.Peek(sniffSize)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("reading source file: %v", err)
+	}
+	full := len(head) == sniffSize
+
+	enc := o.encoding
+	switch {
+	case bytes.HasPrefix(head, []byte("\xEF\xBB\xBF")):
+		br.Discard(3)
+		head = head[3:]
+	case bytes.HasPrefix(head, []byte("\xFF\xFE")), bytes.HasPrefix(head, []byte("\xFE\xFF")):
+		if enc == "" {
+			enc = "utf16"
+		}
+	}
+	if enc == "" {
+		enc = "utf8"
+		if !validUTF8(head, full) {
+			enc = "cp1252"
+		}
+	}
+	var in io.Reader = br
+	if e := encodings[enc]; e != nil {
+		in = e.NewDecoder().Reader(br)
+		head, _ = e.NewDecoder().Bytes(head)
+	}
+	if full {
+		// Only whole lines are examined
+		if i := bytes.LastIndexByte(head, '\n'); i > 0 {
+			head = head[:i+1]
+		}
+	}
+
+	comma := o.delim
+	if comma == 0 {
+		comma = sniffDelim(head)
+	}
+	var lazy bool
+	if o.lazyQuotes != nil {
+		lazy = *o.lazyQuotes
+	} else {
+		lazy = needsLazy(head, comma)
+	}
+
+	var notes []string
+	for _, d := range delims {
+		if d.comma == comma && comma != ',' {
+			notes = append(notes, d.name+" delimited")
+		}
+	}
+	if enc != "utf8" {
+		notes = append(notes, enc)
+	}
+	if lazy {
+		notes = append(notes, "lazy quotes")
+	}
+	if len(notes) > 0 {
+		fmt.Fprintf(status, "Reading input as %v\n", strings.Join(notes, ", "))
+	}
+
+	cr := csv.NewReader(in)
+	cr.Comma = comma
+	cr.LazyQuotes = lazy
+	return cr, nil
+}
+
+// validUTF8 reports whether b is UTF-8, ignoring a rune cut off at the end
+// when b is only the start of the input.
+func validUTF8(b []byte, cut bool) bool {
+	if utf8.Valid(b) {
+		return true
+	}
+	for i := 0; cut && i < utf8.UTFMax-1 && len(b) > 0; i++ {
+		b = b[:len(b)-1]
+		if utf8.Valid(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffDelim picks the delimiter that occurs the same, largest number of
+// times outside quotes on each of the first lines of head, falling back to
+// the one with the highest minimum count and then to comma.
+func sniffDelim(head []byte) rune {
+	lines := strings.Split(strings.TrimRight(string(head), "\r\n"), "\n")
+	if len(lines) > 10 {
+		lines = lines[:10]
+	}
+	best, bestMin, consistent := ',', 0, false
+	for _, d := range delims {
+		lo, hi := -1, 0
+		for _, l := range lines {
+			n, quoted := 0, false
+			for _, c := range l {
+				switch {
+				case c == '"':
+					quoted = !quoted
+				case c == d.comma && !quoted:
+					n++
+				}
+			}
+			if lo < 0 || n < lo {
+				lo = n
+			}
+			if n > hi {
+				hi = n
+			}
+		}
+		if lo <= 0 {
+			continue
+		}
+		same := lo == hi
+		if same && (!consistent || lo > bestMin) || !same && !consistent && lo > bestMin {
+			best, bestMin, consistent = d.comma, lo, same
+		}
+	}
+	return best
+}
+
+// needsLazy reports whether head breaks the strict csv quoting rules.
+func needsLazy(head []byte, comma rune) bool {
+	cr := csv.NewReader(bytes.NewReader(head))
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	for {
+		_, err := cr.Read()
+		if err == nil {
+			continue
+		}
+		if pe, ok := err.(*csv.ParseError); ok {
+			return pe.Err == csv.ErrQuote || pe.Err == csv.ErrBareQuote
+		}
+		return false
+	}
+}
+
+// xlsxReader reads the rows of one worksheet. Blank rows are skipped and
+// short rows are padded to the width of the first row.
+type xlsxReader struct {
+	f     *excelize.File
+	rows  *excelize.Rows
+	width int
+	line  int
+}
+
+// newXLSXReader opens the named worksheet of the workbook in r, or the
+// first one when sheet is empty.
+func newXLSXReader(r io.Reader, sheet string) (*xlsxReader, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read workbook: %v", err)
+	}
+	list := f.GetSheetList()
+	if sheet == "" && len(list) > 0 {
+		sheet = list[0]
+	}
+	if i, _ := f.GetSheetIndex(sheet); i < 0 {
+		f.Close()
+		return nil, fmt.Errorf("workbook has no sheet %q, sheets are: %v", sheet, strings.Join(list, ", "))
+	}
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("reading sheet %v: %v", sheet, err)
+	}
+	return &xlsxReader{f: f, rows: rows}, nil
+}
+
+func (x *xlsxReader) Read() ([]string, error) {
+	for x.rows.Next() {
+		x.line++
+		row, err := x.rows.Columns()
+		if err != nil {
+			return nil, fmt.Errorf("reading row %v: %v", x.line, err)
+		}
+		for len(row) > 0 && strings.TrimSpace(row[len(row)-1]) == "" {
+			row = row[:len(row)-1]
+		}
+		if len(row) == 0 {
+			continue
+		}
+		if x.width == 0 {
+			x.width = len(row)
+		}
+		if len(row) > x.width {
+			return row, &csv.ParseError{StartLine: x.line, Line: x.line, Column: x.width + 1, Err: csv.ErrFieldCount}
+		}
+		for len(row) < x.width {
+			row = append(row, "")
+		}
+		return row, nil
+	}
+	if err := x.rows.Error(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Close releases the workbook.
+func (x *xlsxReader) Close() error {
+	x.rows.Close()
+	return x.f.Close()
+}
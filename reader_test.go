@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestSniffDelim(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want rune
+	}{
+		{"comma", "a,b,c\n1,2,3\n", ','},
+		{"tab", "a\tb\tc\n1\t2\t3\n", '\t'},
+		{"pipe", "a|b|c\n1|2|3\n", '|'},
+		{"semicolon", "a;b;c\n1;2;3\n", ';'},
+		{"quoted commas", "a|b\n\"x, y, z\"|2\n", '|'},
+		{"consistent wins", "a,b;c\n1;2,3,4\n5,6;7\n", ';'},
+		{"single column", "a\nb\n", ','},
+	}
+	for _, tt := range tests {
+		if got := sniffDelim([]byte(tt.head)); got != tt.want {
+			t.Errorf("%s: sniffDelim = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNeedsLazy(t *testing.T) {
+	tests := []struct {
+		name  string
+		head  string
+		comma rune
+		want  bool
+	}{
+		{"clean", "a,b\n\"x, y\",2\n", ',', false},
+		{"bare quote", "a,b\nsay \"hi\",2\n", ',', true},
+		{"stray quote after field", "a,b\n\"x\"y,2\n", ',', true},
+		{"ragged rows", "a,b\n1,2,3\n", ',', false},
+		{"tab", "a\tb\n5\" pipe\t2\n", '\t', true},
+	}
+	for _, tt := range tests {
+		if got := needsLazy([]byte(tt.head), tt.comma); got != tt.want {
+			t.Errorf("%s: needsLazy = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package record
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Alias lists the source header patterns that feed one output field.
+type Alias struct {
+	Field    string
+	Patterns []string
+}
+
+// Aliases is the header detection table. Vendor entries, keyed by the
+// config.json Vendor, are tried before the Default ones.
+type Aliases struct {
+	Default []Alias
+	Vendors map[string][]Alias
+}
+
+// DefaultAliases is used when the resource directory has no aliases.json.
+// Patterns are tried in order and the first match wins.
+var DefaultAliases = []Alias{
+	{"customerid", []string{`(?i)cust.+id`}},
+	{"fullname", []string{`(?i)ful.+me`}},
+	{"firstname", []string{`(?i)fir.+me`, `(?i)^owner.?first`}},
+	{"mi", []string{`(?i)^mi$`}},
+	{"lastname", []string{`(?i)las.+me`, `(?i)^owner.?last`}},
+	{"address1", []string{`(?i)^address$`, `(?i)addr.+1`}},
+	{"address2", []string{`(?i)addr.+2`}},
+	{"city", []string{`(?i)^city$`}},
+	{"state", []string{`(?i)^state$`}},
+	{"zip", []string{`(?i)^zip$`, `(?i)^zip.?code$`, `(?i)^postal(.?code)?$`}},
+	{"zip4", []string{`(?i)^4zip$`, `(?i)^zip4$`}},
+	{"hph", []string{`(?i)^hph$`}},
+	{"bph", []string{`(?i)^bph$`}},
+	{"cph", []string{`(?i)^cph$`}},
+	{"email", []string{`(?i)^email$`}},
+	{"vin", []string{`(?i)^vin$`}},
+	{"year", []string{`(?i)^year$`, `(?i)^vyr$`, `(?i)^model.?year$`}},
+	{"make", []string{`(?i)^make$`, `(?i)^vmk$`}},
+	{"model", []string{`(?i)^model$`, `(?i)^vmd$`}},
+	{"deldate", []string{`(?i)^DelDate$`, `(?i)^sold.?date$`}},
+	{"date", []string{`(?i)^Date$`}},
+	{"dsfwalkseq", []string{`(?i)^DSF_WALK_SEQ$`}},
+	{"crrt", []string{`(?i)^Crrt$`}},
+	{"kbb", []string{`(?i)^KBB$`}},
+}
+
+// alias is an Alias with its patterns compiled.
+type alias struct {
+	field string
+	re    []*regexp.Regexp
+}
+
+func (a alias) match(h string) bool {
+	for _, re := range a.re {
+		if re.MatchString(h) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadAliases reads aliases.json from the resource directory, falling back
+// to DefaultAliases when the file does not exist.
+func loadAliases(rescPath string) (Aliases, error) {
+	f, err := os.Open(filepath.Join(rescPath, "aliases.json"))
+	if os.IsNotExist(err) {
+		return Aliases{Default: DefaultAliases}, nil
+	}
+	if err != nil {
+		return Aliases{}, fmt.Errorf("cannot open aliases.json: %v", err)
+	}
+	defer f.Close()
+
+	var a Aliases
+	if err := json.NewDecoder(f).Decode(&a); err != nil {
+		return Aliases{}, fmt.Errorf("decoding aliases.json: %v", err)
+	}
+	if len(a.Default) == 0 {
+		a.Default = DefaultAliases
+	}
+	return a, nil
+}
+
+// compile returns the vendor aliases followed by the default ones.
+func (a Aliases) compile(vendor string) ([]alias, error) {
+	var list []Alias
+	for v, al := range a.Vendors {
+		if strings.EqualFold(v, vendor) {
+			list = append(list, al...)
+		}
+	}
+	list = append(list, a.Default...)
+
+	var c []alias
+	for _, al := range list {
+		ca := alias{field: lCase(al.Field)}
+		for _, p := range al.Patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("alias for %v: %v", al.Field, err)
+			}
+			ca.re = append(ca.re, re)
+		}
+		c = append(c, ca)
+	}
+	return c, nil
+}
+
+// ColumnReport lists the source columns that matched no alias and the
+// aliased output columns that no source column feeds. Columns that a
+// configured stage fills in from other columns are not listed as empty.
+func (p *Processor) ColumnReport(header []string, c Columns) (unmatched, empty []string) {
+	used := make(map[int]bool)
+	for _, src := range c.src {
+		used[src] = true
+	}
+	for i, h := range header {
+		if !used[i] {
+			unmatched = append(unmatched, h)
+		}
+	}
+	gen := p.generated(c)
+	seen := make(map[string]bool)
+	for _, a := range p.aliases {
+		i, ok := p.hdr[a.field]
+		if !ok || seen[a.field] || !p.inOutput(i) || gen[a.field] {
+			continue
+		}
+		seen[a.field] = true
+		if _, ok := c.src[i]; !ok {
+			empty = append(empty, a.field)
+		}
+	}
+	sort.Strings(empty)
+	return unmatched, empty
+}
+
+// generated returns the fields that the configured stages derive from
+// the source columns in c.
+func (p *Processor) generated(c Columns) map[string]bool {
+	fed := func(fields ...string) bool {
+		for _, f := range fields {
+			if _, ok := c.src[p.hdr[f]]; ok {
+				return true
+			}
+		}
+		return false
+	}
+	gen := make(map[string]bool)
+	for _, s := range p.stages {
+		switch s.Name() {
+		case "customerid":
+			gen["customerid"] = true
+		case "name":
+			if fed("firstname", "lastname") {
+				gen["fullname"] = true
+			}
+			if fed("fullname") {
+				gen["firstname"], gen["mi"], gen["lastname"] = true, true, true
+			}
+		case "address":
+			gen["addressfull"] = true
+		case "phone":
+			if fed("hph", "bph", "cph") {
+				gen["phone"] = true
+			}
+		}
+	}
+	return gen
+}
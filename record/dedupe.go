@@ -0,0 +1,150 @@
+package record
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// dedupeKeys are the match keys Config.Dedupe can name. Each returns ""
+// when the record has nothing to match on.
+var dedupeKeys = map[string]func(r Record) string{
+	"name": func(r Record) string {
+		if r.Get("lastname") == "" || r.Get("address1") == "" || r.Get("zip") == "" {
+			return ""
+		}
+		return strings.Join([]string{lCase(r.Get("firstname")), lCase(r.Get("lastname")),
+			lCase(StdAddress(r.Get("address1"))), r.Get("zip")}, "|")
+	},
+	"email": func(r Record) string { return lCase(r.Get("email")) },
+	"phone": func(r Record) string { return digits(r.Get("phone")) },
+	"vin":   func(r Record) string { return uCase(r.Get("vin")) },
+}
+
+// Duplicate links a record dropped by Dedupe to the record kept for it.
+type Duplicate struct {
+	Dropped Record
+	Kept    Record
+	Key     string // match key that linked them
+}
+
+// checkDedupe validates the Dedupe and DedupeKeep settings.
+func (cfg Config) checkDedupe() error {
+	var bad []string
+	for _, k := range cfg.Dedupe {
+		if _, ok := dedupeKeys[lCase(k)]; !ok {
+			bad = append(bad, fmt.Sprintf("%q", k))
+		}
+	}
+	if len(bad) > 0 {
+		return fmt.Errorf("unknown Dedupe keys in config.json: %v, use name, email, phone or vin", strings.Join(bad, ", "))
+	}
+	switch lCase(cfg.DedupeKeep) {
+	case "", "recent", "complete":
+	default:
+		return fmt.Errorf("invalid DedupeKeep %q in config.json, use recent or complete", cfg.DedupeKeep)
+	}
+	return nil
+}
+
+// Deduping reports whether Config.Dedupe asks for duplicates to be dropped.
+func (p *Processor) Deduping() bool {
+	return len(p.cfg.Dedupe) > 0
+}
+
+// Dedupe drops the records in recs that share a Config.Dedupe match key
+// with another record. The keys are taken in order; for each one the
+// records left with the same value form a group and all but one of it are
+// dropped, chosen by Config.DedupeKeep: the most recent date or deldate
+// ("recent", the default) or the most fields filled in ("complete"), then
+// the earliest row. The survivor takes on a suppression, with its maildnq
+// and blitzdnq flags, found on any of its duplicates. Records already
+// dropped are left out. recs is updated in place.
+func (p *Processor) Dedupe(recs []Record) []Duplicate {
+	var dups []Duplicate
+	for _, k := range p.cfg.Dedupe {
+		k = lCase(k)
+		var order []string
+		groups := make(map[string][]int)
+		for i, r := range recs {
+			if r.Reject != "" {
+				continue
+			}
+			v := dedupeKeys[k](r)
+			if v == "" {
+				continue
+			}
+			if _, ok := groups[v]; !ok {
+				order = append(order, v)
+			}
+			groups[v] = append(groups[v], i)
+		}
+		for _, v := range order {
+			g := groups[v]
+			if len(g) < 2 {
+				continue
+			}
+			keep := g[0]
+			for _, i := range g[1:] {
+				if p.better(recs[i], recs[keep]) {
+					keep = i
+				}
+			}
+			for _, i := range g {
+				if recs[keep].Suppress == "" && recs[i].Suppress != "" {
+					recs[keep].Suppress = recs[i].Suppress
+					for _, f := range []string{"maildnq", "blitzdnq"} {
+						recs[keep].Fields[p.hdr[f]] = recs[i].Fields[p.hdr[f]]
+					}
+				}
+			}
+			for _, i := range g {
+				if i == keep {
+					continue
+				}
+				recs[i].Drop("Duplicate")
+				dups = append(dups, Duplicate{Dropped: recs[i], Kept: recs[keep], Key: k})
+			}
+		}
+	}
+	sort.Slice(dups, func(i, j int) bool { return dups[i].Dropped.Counter < dups[j].Dropped.Counter })
+	return dups
+}
+
+// better reports whether a should survive over b, which comes earlier in
+// the file.
+func (p *Processor) better(a, b Record) bool {
+	ra, rb := recent(a), recent(b)
+	ca, cb := filled(a), filled(b)
+	if lCase(p.cfg.DedupeKeep) == "complete" {
+		return ca > cb || ca == cb && ra > rb
+	}
+	return ra > rb || ra == rb && ca > cb
+}
+
+// recent returns the later of the record's date and deldate as YYYYMMDD,
+// 0 when neither is set.
+func recent(r Record) int {
+	var max int
+	for _, f := range [][3]string{{"lsdyear", "lsdmonth", "lsdday"}, {"dldyear", "dldmonth", "dldday"}} {
+		y, _ := strconv.Atoi(r.Get(f[0]))
+		m, _ := strconv.Atoi(r.Get(f[1]))
+		d, _ := strconv.Atoi(r.Get(f[2]))
+		if v := y*10000 + m*100 + d; v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+// filled counts the fields of r that hold a value.
+func filled(r Record) int {
+	var n int
+	for _, v := range r.Fields {
+		if strings.TrimSpace(v) != "" {
+			n++
+		}
+	}
+	return n
+}
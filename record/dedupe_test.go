@@ -0,0 +1,110 @@
+package record
+
+import "testing"
+
+// testRecord returns a record at source row n with the named fields set
+// from kv, which alternates field names and values.
+func testRecord(n int, kv ...string) Record {
+	r := Record{Counter: n, Fields: make([]string, len(KnownFields)), hdr: fieldIndex}
+	for i := 0; i+1 < len(kv); i += 2 {
+		r.Fields[fieldIndex[kv[i]]] = kv[i+1]
+	}
+	return r
+}
+
+func TestDedupe(t *testing.T) {
+	zed := []string{"firstname", "Zed", "lastname", "Doe", "address1", "1 Elm St", "zip", "92618"}
+	type link struct {
+		dropped, kept int
+		key           string
+	}
+	tests := []struct {
+		name  string
+		cfg   Config
+		recs  []Record
+		links []link
+	}{
+		{
+			name: "earliest kept on equal records",
+			cfg:  Config{Dedupe: []string{"email"}},
+			recs: []Record{
+				testRecord(1, "email", "a@x.com"),
+				testRecord(2, "email", "A@X.COM"),
+				testRecord(3, "email", "b@x.com"),
+			},
+			links: []link{{2, 1, "email"}},
+		},
+		{
+			name: "most recent kept",
+			cfg:  Config{Dedupe: []string{"vin"}},
+			recs: []Record{
+				testRecord(1, "vin", "1HGCM", "dldyear", "2015", "dldmonth", "01", "dldday", "02"),
+				testRecord(2, "vin", "1hgcm", "lsdyear", "2016", "lsdmonth", "03", "lsdday", "04"),
+			},
+			links: []link{{1, 2, "vin"}},
+		},
+		{
+			name: "most complete kept",
+			cfg:  Config{Dedupe: []string{"phone"}, DedupeKeep: "complete"},
+			recs: []Record{
+				testRecord(1, "phone", "(714) 555-1212", "dldyear", "2019"),
+				testRecord(2, "phone", "714-555-1212", "email", "a@x.com", "city", "Irvine"),
+			},
+			links: []link{{1, 2, "phone"}},
+		},
+		{
+			name: "each pair names the key that links it",
+			cfg:  Config{Dedupe: []string{"email", "name"}},
+			recs: []Record{
+				testRecord(1, append([]string{"email", "z@x.com"}, zed...)...),
+				testRecord(2, "email", "z@x.com", "lastname", "Roe"),
+				testRecord(3, append([]string{"email", "other@x.com"}, zed...)...),
+				testRecord(4, append([]string{"email", "other@x.com"}, zed...)...),
+			},
+			links: []link{{2, 1, "email"}, {3, 1, "name"}, {4, 3, "email"}},
+		},
+		{
+			name: "rejected records left out",
+			cfg:  Config{Dedupe: []string{"email"}},
+			recs: func() []Record {
+				r := []Record{testRecord(1, "email", "a@x.com"), testRecord(2, "email", "a@x.com")}
+				r[0].Drop("MaxRadius")
+				return r
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		p := &Processor{cfg: tt.cfg, hdr: fieldIndex}
+		dups := p.Dedupe(tt.recs)
+		if len(dups) != len(tt.links) {
+			t.Errorf("%s: got %d duplicates, want %d", tt.name, len(dups), len(tt.links))
+			continue
+		}
+		for i, d := range dups {
+			got := link{d.Dropped.Counter, d.Kept.Counter, d.Key}
+			if got != tt.links[i] {
+				t.Errorf("%s: duplicate %d = %+v, want %+v", tt.name, i, got, tt.links[i])
+			}
+			if r := tt.recs[d.Dropped.Counter-1]; r.Reject != "Duplicate" {
+				t.Errorf("%s: row %d Reject = %q, want Duplicate", tt.name, r.Counter, r.Reject)
+			}
+		}
+	}
+}
+
+func TestDedupeKeepsSuppression(t *testing.T) {
+	recs := []Record{
+		testRecord(1, "email", "a@x.com", "lsdyear", "2020"),
+		testRecord(2, "email", "a@x.com", "maildnq", "GenS", "blitzdnq", "GenS"),
+	}
+	recs[1].Suppress = "GenS name"
+	p := &Processor{cfg: Config{Dedupe: []string{"email"}}, hdr: fieldIndex}
+	p.Dedupe(recs)
+	kept := recs[0]
+	if kept.Reject != "" || kept.Suppress != "GenS name" {
+		t.Fatalf("kept Reject = %q, Suppress = %q, want the suppression of row 2", kept.Reject, kept.Suppress)
+	}
+	if kept.Get("maildnq") != "GenS" || kept.Get("blitzdnq") != "GenS" {
+		t.Errorf("kept maildnq = %q, blitzdnq = %q, want GenS", kept.Get("maildnq"), kept.Get("blitzdnq"))
+	}
+}
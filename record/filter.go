@@ -0,0 +1,43 @@
+package record
+
+import "strconv"
+
+// filter drops the record for the first Config rule it breaks. Limits left
+// at zero in config.json are not enforced, and a rule is skipped when the
+// record has no usable value for it.
+func (p *Processor) filter(r Record) (Record, error) {
+	c := p.cfg
+	hdr := p.hdr
+	rec := r.Fields
+	if rad, err := strconv.ParseFloat(rec[hdr["radius"]], 64); err == nil && c.MaxRadius > 0 && rad > float64(c.MaxRadius) {
+		r.Drop("MaxRadius")
+		return r, nil
+	}
+	if yr, err := strconv.Atoi(decYr(rec[hdr["year"]])); err == nil {
+		switch {
+		case c.MinVehYear > 0 && yr < c.MinVehYear:
+			r.Drop("MinVehYear")
+			return r, nil
+		case c.MaxVehYear > 0 && yr > c.MaxVehYear:
+			r.Drop("MaxVehYear")
+			return r, nil
+		}
+	}
+	if yr, err := strconv.Atoi(rec[hdr["dldyear"]]); err == nil {
+		switch {
+		case c.MinYearDelDate > 0 && yr < c.MinYearDelDate:
+			r.Drop("MinYearDelDate")
+			return r, nil
+		case c.MaxYearDelDate > 0 && yr > c.MaxYearDelDate:
+			r.Drop("MaxYearDelDate")
+			return r, nil
+		}
+	}
+	switch {
+	case c.DelBlankDATE && rec[hdr["date"]] == "":
+		r.Drop("DelBlankDATE")
+	case c.DelBlankDELDATE && rec[hdr["deldate"]] == "":
+		r.Drop("DelBlankDELDATE")
+	}
+	return r, nil
+}
@@ -0,0 +1,93 @@
+package record
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func tCase(f string) string {
+	return strings.TrimSpace(strings.Title(strings.ToLower(f)))
+}
+
+func uCase(f string) string {
+	return strings.TrimSpace(strings.ToUpper(f))
+}
+
+func lCase(f string) string {
+	return strings.TrimSpace(strings.ToLower(f))
+}
+
+func cInt(f string) (int, error) {
+	i, err := strconv.Atoi(f)
+	if err != nil {
+		return 0, fmt.Errorf("converting string to int %v: %v", f, err)
+	}
+	return i, nil
+}
+
+// StdAddress collapses whitespace and title-cases a street address.
+func StdAddress(f string) string {
+	return strings.Title(strings.ToLower(strings.Join(strings.Fields(f), " ")))
+}
+
+func decYr(y string) string {
+	// YearDecodeDict is a map of 2-Digit abbreviated Years
+	yrDecDict := map[string]string{"0": "2000", "1": "2001", "2": "2002",
+		"3": "2003", "4": "2004", "5": "2005", "6": "2006", "7": "2007",
+		"8": "2008", "9": "2009", "10": "2010", "11": "2011", "12": "2012",
+		"13": "2013", "14": "2014", "15": "2015", "16": "2016", "17": "2017",
+		"18": "2018", "19": "2019", "20": "2020", "40": "1940", "41": "1941",
+		"42": "1942", "43": "1943", "44": "1944", "45": "1945", "46": "1946",
+		"47": "1947", "48": "1948", "49": "1949", "50": "1950", "51": "1951",
+		"52": "1952", "53": "1953", "54": "1954", "55": "1955", "56": "1956",
+		"57": "1957", "58": "1958", "59": "1959", "60": "1960", "61": "1961",
+		"62": "1962", "63": "1963", "64": "1964", "65": "1965", "66": "1966",
+		"67": "1967", "68": "1968", "69": "1969", "70": "1970", "71": "1971",
+		"72": "1972", "73": "1973", "74": "1974", "75": "1975", "76": "1976",
+		"77": "1977", "78": "1978", "79": "1979", "80": "1980", "81": "1981",
+		"82": "1982", "83": "1983", "84": "1984", "85": "1985", "86": "1986",
+		"87": "1987", "88": "1988", "89": "1989", "90": "1990", "91": "1991",
+		"92": "1992", "93": "1993", "94": "1994", "95": "1995", "96": "1996",
+		"97": "1997", "98": "1998", "99": "1999"}
+	if dy, ok := yrDecDict[y]; ok {
+		return dy
+	}
+	return y
+}
+
+// ReformatPhone formats a 10 or 7 digit phone number as (999) 999-9999 or
+// 999-9999, returning "" for anything else.
+func ReformatPhone(p string) string {
+	sep := []string{"-", ".", "*", "(", ")"}
+	for _, v := range sep {
+		p = strings.Replace(p, v, "", -1)
+	}
+	p = strings.Replace(p, " ", "", -1)
+	switch len(p) {
+	case 10:
+		p = fmt.Sprintf("(%v) %v-%v", p[0:3], p[3:6], p[6:10])
+	case 7:
+		p = fmt.Sprintf("%v-%v", p[0:3], p[3:7])
+	default:
+		p = ""
+	}
+	return p
+}
+
+// ParseDate parses d in one of the common dealer date formats and returns
+// the date as YYYY/M/D along with its year, month and day.
+func ParseDate(d string) (string, string, string, string) {
+	if d != "" {
+		formats := []string{"1/2/2006", "1-2-2006", "1/2/06", "1-2-06",
+			"2006/1/2", "2006-1-2"}
+		for _, f := range formats {
+			if t, err := time.Parse(f, d); err == nil {
+				nDate := fmt.Sprintf("%v/%v/%v", strconv.Itoa(t.Year()), strconv.Itoa(int(t.Month())), strconv.Itoa(t.Day()))
+				return nDate, strconv.Itoa(t.Year()), strconv.Itoa(int(t.Month())), strconv.Itoa(t.Day())
+			}
+		}
+	}
+	return "", "", "", ""
+}
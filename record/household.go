@@ -0,0 +1,100 @@
+package record
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Householding reports whether Config.Household asks for household IDs.
+func (p *Processor) Householding() bool {
+	return p.cfg.Household || p.cfg.HouseholdCollapse
+}
+
+// checkHousehold makes sure that householding has an output column for the
+// IDs among fields, which come from src.
+func (cfg Config) checkHousehold(fields []string, src string) error {
+	if !cfg.Household && !cfg.HouseholdCollapse {
+		return nil
+	}
+	for _, f := range fields {
+		if lCase(f) == "householdid" {
+			return nil
+		}
+	}
+	return fmt.Errorf("householding needs householdid in %v of config.json", src)
+}
+
+// householdKey groups records by standardized address, ZIP and last name.
+// It returns "" when any of them is missing.
+func householdKey(r Record) string {
+	if r.Get("address1") == "" || r.Get("zip") == "" || r.Get("lastname") == "" {
+		return ""
+	}
+	return strings.Join([]string{lCase(StdAddress(r.Get("address1"))), lCase(StdAddress(r.Get("address2"))),
+		r.Get("zip"), lCase(r.Get("lastname"))}, "|")
+}
+
+// Household sets householdid on every record that is not dropped, the same
+// ID for records at one address with one last name, numbered in source
+// order. With Config.HouseholdCollapse the first unsuppressed record of a
+// household is kept under the combined names of the others, which are
+// dropped; suppressed records are never merged. recs is updated in place
+// and the number of households is returned.
+func (p *Processor) Household(recs []Record) int {
+	ids := make(map[string]string)
+	members := make(map[string][]int)
+	var n int
+	for i := range recs {
+		r := recs[i]
+		if r.Reject != "" {
+			continue
+		}
+		k := householdKey(r)
+		id, ok := ids[k]
+		if !ok || k == "" {
+			n++
+			id = fmt.Sprintf("H%06d", n)
+			if k != "" {
+				ids[k] = id
+			}
+		}
+		r.Set("householdid", id)
+		if k != "" && r.Suppress == "" {
+			members[k] = append(members[k], i)
+		}
+	}
+	if !p.cfg.HouseholdCollapse {
+		return n
+	}
+	for _, m := range members {
+		if len(m) < 2 {
+			continue
+		}
+		keep := recs[m[0]]
+		var first []string
+		seen := make(map[string]bool)
+		for _, i := range m {
+			f := recs[i].Get("firstname")
+			if f != "" && !seen[lCase(f)] {
+				seen[lCase(f)] = true
+				first = append(first, f)
+			}
+			if i != m[0] {
+				recs[i].Drop("Household")
+			}
+		}
+		if len(first) > 1 {
+			names := joinNames(first)
+			keep.Set("firstname", names)
+			keep.Set("mi", "")
+			keep.Set("fullname", names+" "+keep.Get("lastname"))
+		}
+	}
+	return n
+}
+
+// joinNames lists names as "A & B" or "A, B & C".
+func joinNames(names []string) string {
+	last := len(names) - 1
+	return strings.Join(names[:last], ", ") + " & " + names[last]
+}
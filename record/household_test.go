@@ -0,0 +1,98 @@
+package record
+
+import (
+	"reflect"
+	"testing"
+)
+
+func householdRecords() []Record {
+	recs := []Record{
+		testRecord(1, "firstname", "John", "mi", "Q", "lastname", "Doe", "address1", "1 Elm St", "zip", "92618"),
+		testRecord(2, "firstname", "Jane", "lastname", "DOE", "address1", "1 elm st", "zip", "92618"),
+		testRecord(3, "firstname", "Bob", "lastname", "Roe", "address1", "2 Oak Ave", "zip", "92618"),
+		testRecord(4, "firstname", "Ann", "address1", "1 Elm St", "zip", "92618"),
+		testRecord(5, "firstname", "Joe", "lastname", "Doe", "address1", "1 Elm St", "zip", "92618"),
+		testRecord(6, "firstname", "Sue", "lastname", "Doe", "address1", "1 Elm St", "zip", "92618"),
+	}
+	recs[4].Drop("MaxRadius")
+	recs[5].Suppress = "DNM name"
+	return recs
+}
+
+func TestHousehold(t *testing.T) {
+	recs := householdRecords()
+	p := &Processor{cfg: Config{Household: true}, hdr: fieldIndex}
+	if n := p.Household(recs); n != 3 {
+		t.Errorf("Household = %d households, want 3", n)
+	}
+	var got []string
+	for _, r := range recs {
+		got = append(got, r.Get("householdid"))
+	}
+	want := []string{"H000001", "H000001", "H000002", "H000003", "", "H000001"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("householdid = %v, want %v", got, want)
+	}
+	for _, r := range recs[:4] {
+		if r.Reject != "" {
+			t.Errorf("row %d dropped as %q without HouseholdCollapse", r.Counter, r.Reject)
+		}
+	}
+}
+
+func TestHouseholdCollapse(t *testing.T) {
+	recs := householdRecords()
+	p := &Processor{cfg: Config{HouseholdCollapse: true}, hdr: fieldIndex}
+	p.Household(recs)
+
+	keep := recs[0]
+	if keep.Reject != "" {
+		t.Fatalf("row 1 dropped as %q, want it kept", keep.Reject)
+	}
+	if f := keep.Get("firstname"); f != "John & Jane" {
+		t.Errorf("firstname = %q, want John & Jane", f)
+	}
+	if f := keep.Get("fullname"); f != "John & Jane Doe" {
+		t.Errorf("fullname = %q, want John & Jane Doe", f)
+	}
+	if m := keep.Get("mi"); m != "" {
+		t.Errorf("mi = %q, want it cleared", m)
+	}
+	if r := recs[1].Reject; r != "Household" {
+		t.Errorf("row 2 Reject = %q, want Household", r)
+	}
+	if r := recs[5]; r.Reject != "" || r.Get("householdid") != "H000001" {
+		t.Errorf("suppressed row 6 Reject = %q, householdid = %q, want it kept in H000001", r.Reject, r.Get("householdid"))
+	}
+}
+
+func TestHouseholdOutput(t *testing.T) {
+	has := func(cfg Config) bool {
+		fields, _, _, err := cfg.output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range fields {
+			if f == "householdid" {
+				return true
+			}
+		}
+		return false
+	}
+	if has(Config{}) {
+		t.Error("default output has householdid without householding")
+	}
+	if !has(Config{Household: true}) {
+		t.Error("default output lacks householdid with Household")
+	}
+
+	if err := (Config{Household: true}).checkHousehold([]string{"zip", "HouseholdID"}, "Headers"); err != nil {
+		t.Errorf("checkHousehold with householdid: %v", err)
+	}
+	if err := (Config{HouseholdCollapse: true}).checkHousehold([]string{"zip"}, "Headers"); err == nil {
+		t.Error("checkHousehold without householdid: no error")
+	}
+	if err := (Config{}).checkHousehold([]string{"zip"}, "Headers"); err != nil {
+		t.Errorf("checkHousehold without householding: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package record
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Mapping pins source columns to output fields ahead of alias detection.
+// Keys are source header names, matched case-insensitively, or 0-based
+// column indexes. An empty field ignores the column altogether.
+type Mapping map[string]string
+
+// LoadMapping reads a JSON Mapping file.
+func LoadMapping(path string) (Mapping, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open mapping file: %v", err)
+	}
+	defer f.Close()
+
+	var m Mapping
+	if err := json.NewDecoder(f).Decode(&m); err != nil {
+		return nil, fmt.Errorf("decoding mapping file %v: %v", path, err)
+	}
+	return m, nil
+}
+
+// resolve returns the output field for each source column named in m. A
+// key is looked up as a header name first and as an index second.
+func (m Mapping) resolve(header []string) (map[int]string, error) {
+	cols := make(map[int]string)
+	var bad []string
+	for k, field := range m {
+		i := -1
+		for j, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(k)) {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			if n, err := strconv.Atoi(k); err == nil && n >= 0 && n < len(header) {
+				i = n
+			}
+		}
+		if i < 0 {
+			bad = append(bad, fmt.Sprintf("%q", k))
+			continue
+		}
+		cols[i] = lCase(field)
+	}
+	if len(bad) > 0 {
+		sort.Strings(bad)
+		return nil, fmt.Errorf("mapping source columns not found: %v", strings.Join(bad, ", "))
+	}
+	return cols, nil
+}
+
+// Suggest drafts a Mapping for header using alias detection and, when
+// sample holds data rows, content detection for the remaining columns. It
+// returns source column and output field pairs in column order, with "" for
+// the columns nothing recognized, and the content guesses.
+func (p *Processor) Suggest(header []string, sample [][]string) ([][2]string, []Guess) {
+	c, _ := p.detect(header, nil)
+	var g []Guess
+	if len(sample) > 0 {
+		g = p.guess(header, sample, nil, c)
+	}
+	field := make(map[int]string)
+	for k, v := range p.hdr {
+		if src, ok := c.src[v]; ok {
+			field[src] = k
+		}
+	}
+	s := make([][2]string, len(header))
+	for i, h := range header {
+		s[i] = [2]string{h, field[i]}
+	}
+	return s, g
+}
@@ -0,0 +1,86 @@
+package record
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// miscFields are the output fields that take passthrough columns.
+var miscFields = []string{"misc1", "misc2", "misc3"}
+
+// AppendsPassthrough reports whether passthrough columns are appended after
+// the output fields under their source names, so that the output columns
+// depend on the source file.
+func (p *Processor) AppendsPassthrough() bool {
+	return len(p.cfg.Passthrough) > 0 && p.cfg.PassthroughAppend
+}
+
+// passthrough adds the Config.Passthrough source columns that no field
+// reads yet to c, in source order. They fill the free misc fields or, with
+// PassthroughAppend, extra output columns after the requested ones. "*" stands for
+// every such column except those m ignores. Misc fields left out of the
+// output are not used.
+func (p *Processor) passthrough(header []string, c Columns, m Mapping) error {
+	if len(p.cfg.Passthrough) == 0 {
+		return nil
+	}
+	used := make(map[int]bool)
+	for _, src := range c.src {
+		used[src] = true
+	}
+	pinned, _ := m.resolve(header)
+
+	var all bool
+	want := make(map[string]string)
+	for _, v := range p.cfg.Passthrough {
+		if v == "*" {
+			all = true
+			continue
+		}
+		want[lCase(v)] = v
+	}
+	var cols []int
+	for i, h := range header {
+		_, named := want[lCase(h)]
+		delete(want, lCase(h))
+		if used[i] {
+			continue
+		}
+		if _, ignored := pinned[i]; named || all && !ignored {
+			cols = append(cols, i)
+		}
+	}
+	if len(want) > 0 {
+		var missing []string
+		for _, v := range want {
+			missing = append(missing, fmt.Sprintf("%q", v))
+		}
+		sort.Strings(missing)
+		return fmt.Errorf("passthrough columns not found: %v", strings.Join(missing, ", "))
+	}
+
+	if p.cfg.PassthroughAppend {
+		for k, src := range cols {
+			c.src[len(KnownFields)+k] = src
+			c.pass[len(KnownFields)+k] = true
+		}
+		return nil
+	}
+	var free []int
+	for _, f := range miscFields {
+		i := p.hdr[f]
+		if _, fed := c.src[i]; !fed && p.inOutput(i) {
+			free = append(free, i)
+		}
+	}
+	if len(cols) > len(free) {
+		return fmt.Errorf("%v passthrough columns but only %v free misc fields, set PassthroughAppend to keep them all",
+			len(cols), len(free))
+	}
+	for k, src := range cols {
+		c.src[free[k]] = src
+		c.pass[free[k]] = true
+	}
+	return nil
+}
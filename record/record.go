@@ -0,0 +1,229 @@
+// Package record cleanses and standardizes dealer and prospect mail
+// records. A Processor built from a Config and the shared Resources maps
+// source columns onto the output layout and normalizes each row.
+package record
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rssenar/monju/geo"
+)
+
+// Record is one row moving through the pipeline.
+type Record struct {
+	Counter  int      // source row number, 1 for the first data row
+	Fields   []string // values in KnownFields order, then passthrough columns
+	Suppress string   // reason the record matched a suppression list
+	Reject   string   // reason a stage dropped the record
+
+	hdr  map[string]int
+	pass map[int]bool // Fields positions fed by passthrough
+}
+
+// Get returns the value of the named output field, or "" when the field is
+// not part of the output.
+func (r Record) Get(key string) string {
+	if i, ok := r.hdr[key]; ok && i < len(r.Fields) {
+		return r.Fields[i]
+	}
+	return ""
+}
+
+// Set stores v in the named output field. It does nothing when the field
+// is not part of the output.
+func (r Record) Set(key, v string) {
+	if i, ok := r.hdr[key]; ok && i < len(r.Fields) {
+		r.Fields[i] = v
+	}
+}
+
+// Drop marks the record as dropped for reason; Process stops there.
+func (r *Record) Drop(reason string) {
+	r.Reject = reason
+}
+
+// Config holds the job settings read from config.json.
+type Config struct {
+	CentZip         int
+	MaxRadius       int
+	MaxVehYear      int
+	MinVehYear      int
+	MaxYearDelDate  int
+	MinYearDelDate  int
+	Vendor          string
+	Source          string
+	DelBlankDATE    bool
+	DelBlankDELDATE bool
+	Headers         []string // output columns, all KnownFields when empty
+	Stages          []string
+
+	Profiles map[string]Profile // named output layouts used instead of Headers
+	Profile  string             // profile in use, -profile overrides it
+
+	Dedupe     []string // match keys for dropping duplicates: name, email, phone, vin
+	DedupeKeep string   // survivor of a duplicate group: recent (default) or complete
+
+	Household         bool // set householdid on each record
+	HouseholdCollapse bool // also keep one record per household with combined names
+
+	Passthrough       []string // source columns to keep, "*" for all unrecognized ones
+	PassthroughAppend bool     // append them after Headers instead of using misc1-3
+}
+
+// Columns maps a Record field position to the source column it is read
+// from. Positions past the end of KnownFields are appended passthrough
+// columns.
+type Columns struct {
+	src  map[int]int
+	pass map[int]bool // positions fed by passthrough, kept as read
+}
+
+func newColumns() Columns {
+	return Columns{src: make(map[int]int), pass: make(map[int]bool)}
+}
+
+// Processor normalizes records for one Config. It only reads its Resources
+// and is safe for concurrent use.
+type Processor struct {
+	cfg     Config
+	res     *Resources
+	hdr     map[string]int
+	out     []int
+	header  []string
+	aliases []alias
+	stages  []Stage
+}
+
+// New returns a Processor for cfg. The central ZIP code must be present in
+// the coordinate table since every radius is measured from it.
+func New(cfg Config, res *Resources) (*Processor, error) {
+	if _, ok := res.cord[geo.ValZip(strconv.Itoa(cfg.CentZip))]; !ok {
+		return nil, fmt.Errorf("invalid central zip code %v", cfg.CentZip)
+	}
+	p := &Processor{cfg: cfg, res: res, hdr: fieldIndex}
+	fields, labels, src, err := cfg.output()
+	if err != nil {
+		return nil, err
+	}
+	if p.out, err = schema(fields, src); err != nil {
+		return nil, err
+	}
+	p.header = labels
+	if err := cfg.checkDedupe(); err != nil {
+		return nil, err
+	}
+	if err := cfg.checkHousehold(fields, src); err != nil {
+		return nil, err
+	}
+	if p.aliases, err = res.aliases.compile(cfg.Vendor); err != nil {
+		return nil, err
+	}
+	names := cfg.Stages
+	if len(names) == 0 {
+		names = DefaultStages
+	}
+	for _, n := range names {
+		s, err := p.stage(n)
+		if err != nil {
+			return nil, err
+		}
+		p.stages = append(p.stages, s)
+	}
+	return p, nil
+}
+
+// Process runs r through the configured stages in order. It stops early
+// when a stage drops the record, leaving the reason in r.Reject.
+func (p *Processor) Process(r Record) (Record, error) {
+	for _, s := range p.stages {
+		var err error
+		if r, err = s.Run(r); err != nil {
+			return r, err
+		}
+		if r.Reject != "" {
+			break
+		}
+	}
+	return r, nil
+}
+
+// Columns returns where each output field is read from. Source columns
+// named in m are placed first; the rest are matched against the column
+// aliases and what is left over is passed through when configured. m may
+// be nil. A ZIP column is required.
+func (p *Processor) Columns(header []string, m Mapping) (Columns, error) {
+	c, err := p.detect(header, m)
+	if err != nil {
+		return Columns{}, err
+	}
+	if err := p.passthrough(header, c, m); err != nil {
+		return Columns{}, err
+	}
+	if _, ok := c.src[p.hdr["zip"]]; !ok {
+		return Columns{}, errors.New("ZIP code is a required field")
+	}
+	return c, nil
+}
+
+// detect does the work of Columns without requiring a ZIP column.
+func (p *Processor) detect(header []string, m Mapping) (Columns, error) {
+	c := newColumns()
+	pinned, err := m.resolve(header)
+	if err != nil {
+		return Columns{}, err
+	}
+	fixed := make(map[int]bool)
+	var unknown []string
+	for src, field := range pinned {
+		if field == "" {
+			continue
+		}
+		idx, ok := p.hdr[field]
+		if !ok {
+			unknown = append(unknown, field)
+			continue
+		}
+		c.src[idx] = src
+		fixed[idx] = true
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return Columns{}, fmt.Errorf("mapping to unknown fields: %v", strings.Join(unknown, ", "))
+	}
+
+	for i, v := range header {
+		if _, ok := pinned[i]; ok {
+			continue
+		}
+		for _, a := range p.aliases {
+			if !a.match(v) {
+				continue
+			}
+			if idx, ok := p.hdr[a.field]; ok && !fixed[idx] {
+				c.src[idx] = i
+			}
+			break
+		}
+	}
+	return c, nil
+}
+
+// NewRecord lays out a source row in Record order using the Columns found
+// by Columns. counter is the source row number.
+func (p *Processor) NewRecord(counter int, row []string, m Columns) Record {
+	n := len(KnownFields)
+	for i := range m.src {
+		if i >= n {
+			n = i + 1
+		}
+	}
+	nr := make([]string, n)
+	for i, src := range m.src {
+		nr[i] = row[src]
+	}
+	return Record{Counter: counter, Fields: nr, hdr: p.hdr, pass: m.pass}
+}
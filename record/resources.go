@@ -0,0 +1,289 @@
+package record
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rssenar/monju/geo"
+)
+
+// ResourceFiles lists the files required in the resource directory. An
+// aliases.json file is read too when present.
+var ResourceFiles = []string{
+	"config.json",
+	"USZIPCoordinates.csv",
+	"SCFFacilites.csv",
+	"DDUFacilites.csv",
+	"HispLNames.csv",
+	"DoNotMail.csv",
+	"_GeneralSuppression.csv",
+	"_GeneralSuppressionNames.csv",
+}
+
+// Resources holds the lookup tables and column aliases shared by every
+// Processor. They are loaded once and only read afterwards.
+type Resources struct {
+	cord    map[string][]string
+	scfFac  map[string]string
+	dduFac  map[string]string
+	hist    map[string]int
+	dnm     dnmList
+	genS    map[string]int
+	genSNm  map[string]int
+	aliases Aliases
+}
+
+// LoadResources reads the lookup tables from the resource directory. When
+// loaded is not nil it is called with the time taken by each file, or with
+// what was used instead of an optional file that is absent.
+func LoadResources(rescPath string, loaded func(file string, d time.Duration)) (*Resources, error) {
+	res := &Resources{}
+	loaders := []struct {
+		file     string
+		load     func() error
+		fallback string // reported instead of file when it is absent
+	}{
+		{"USZIPCoordinates.csv", func() (err error) { res.cord, err = loadZipCor(rescPath); return }, ""},
+		{"SCFFacilites.csv", func() (err error) { res.scfFac, err = loadSCFFac(rescPath); return }, ""},
+		{"DDUFacilites.csv", func() (err error) { res.dduFac, err = loadDDUFac(rescPath); return }, ""},
+		{"HispLNames.csv", func() (err error) { res.hist, err = loadHist(rescPath); return }, ""},
+		{"DoNotMail.csv", func() (err error) { res.dnm, err = loadDNM(rescPath); return }, ""},
+		{"_GeneralSuppression.csv", func() (err error) { res.genS, err = loadGenS(rescPath); return }, ""},
+		{"_GeneralSuppressionNames.csv", func() (err error) { res.genSNm, err = loadGenSNm(rescPath); return }, ""},
+		{"aliases.json", func() (err error) { res.aliases, err = loadAliases(rescPath); return }, "default aliases, no aliases.json found"},
+	}
+	for _, l := range loaders {
+		t := time.Now()
+		if err := l.load(); err != nil {
+			return nil, err
+		}
+		if loaded == nil {
+			continue
+		}
+		file := l.file
+		if l.fallback != "" {
+			if _, err := os.Stat(filepath.Join(rescPath, l.file)); os.IsNotExist(err) {
+				file = l.fallback
+			}
+		}
+		loaded(file, time.Since(t))
+	}
+	return res, nil
+}
+
+// LoadConfig reads config.json from the resource directory.
+func LoadConfig(rescPath string) (Config, error) {
+	conf, err := os.Open(filepath.Join(rescPath, "config.json"))
+	if err != nil {
+		return Config{}, fmt.Errorf("cannot open config.json: %v", err)
+	}
+	defer conf.Close()
+
+	var param Config
+
+	jsonParser := json.NewDecoder(conf)
+	if err = jsonParser.Decode(&param); err != nil {
+		return Config{}, fmt.Errorf("decoding config.json: %v", err)
+	}
+	return param, nil
+}
+
+func loadZipCor(rescPath string) (map[string][]string, error) {
+	cord := make(map[string][]string)
+
+	zipCor, err := os.Open(filepath.Join(rescPath, "USZIPCoordinates.csv"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open USZIPCoordinates.csv: %v", err)
+	}
+	defer zipCor.Close()
+	rdr := csv.NewReader(zipCor)
+	for {
+		z, err := rdr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading USZIPCoordinates.csv: %v", err)
+		}
+		cord[z[0]] = []string{z[1], z[2]}
+	}
+	return cord, nil
+}
+
+func loadSCFFac(rescPath string) (map[string]string, error) {
+	scf := make(map[string]string)
+
+	f, err := os.Open(filepath.Join(rescPath, "SCFFacilites.csv"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open SCFFacilites.csv: %v", err)
+	}
+	defer f.Close()
+	rdr := csv.NewReader(f)
+	for {
+		s, err := rdr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading SCFFacilites.csv: %v", err)
+		}
+		scf[s[0]] = s[1]
+	}
+	return scf, nil
+}
+
+func loadDDUFac(rescPath string) (map[string]string, error) {
+	ddu := make(map[string]string)
+
+	f, err := os.Open(filepath.Join(rescPath, "DDUFacilites.csv"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open DDUFacilites.csv: %v", err)
+	}
+	defer f.Close()
+	rdr := csv.NewReader(f)
+	for {
+		s, err := rdr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading DDUFacilites.csv: %v", err)
+		}
+		ddu[s[0]] = s[1]
+	}
+	return ddu, nil
+}
+
+func loadHist(rescPath string) (map[string]int, error) {
+	hisp := make(map[string]int)
+
+	f, err := os.Open(filepath.Join(rescPath, "HispLNames.csv"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open HispLNames.csv: %v", err)
+	}
+	defer f.Close()
+	rdr := csv.NewReader(f)
+	for {
+		s, err := rdr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading HispLNames.csv: %v", err)
+		}
+		hisp[tCase(s[0])]++
+	}
+	return hisp, nil
+}
+
+func loadDNM(rescPath string) (dnmList, error) {
+	dnm := dnmList{
+		name:   make(map[string]int),
+		adrZip: make(map[string]int),
+		email:  make(map[string]int),
+		phone:  make(map[string]int),
+	}
+
+	f, err := os.Open(filepath.Join(rescPath, "DoNotMail.csv"))
+	if err != nil {
+		return dnmList{}, fmt.Errorf("cannot open DoNotMail.csv: %v", err)
+	}
+	defer f.Close()
+	rdr := csv.NewReader(f)
+	rdr.FieldsPerRecord = -1
+	// Files without a header row carry the full name in the first column
+	col := map[string]int{"name": 0}
+	for i := 0; ; i++ {
+		s, err := rdr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return dnmList{}, fmt.Errorf("reading DoNotMail.csv: %v", err)
+		}
+		if i == 0 {
+			if c, ok := dnmCols(s); ok {
+				col = c
+				continue
+			}
+		}
+		dnm.add(s, col)
+	}
+	return dnm, nil
+}
+
+func loadGenS(rescPath string) (map[string]int, error) {
+	gen := make(map[string]int)
+
+	f, err := os.Open(filepath.Join(rescPath, "_GeneralSuppression.csv"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open _GeneralSuppression.csv: %v", err)
+	}
+	defer f.Close()
+	rdr := csv.NewReader(f)
+	for {
+		s, err := rdr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading _GeneralSuppression.csv: %v", err)
+		}
+		adrZip := fmt.Sprintf("%v %v", StdAddress(s[2]), geo.ValZip(strings.TrimSpace(s[5])))
+		gen[adrZip]++
+	}
+	return gen, nil
+}
+
+func loadGenSNm(rescPath string) (map[string]int, error) {
+	gen := make(map[string]int)
+
+	f, err := os.Open(filepath.Join(rescPath, "_GeneralSuppressionNames.csv"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open _GeneralSuppressionNames.csv: %v", err)
+	}
+	defer f.Close()
+	rdr := csv.NewReader(f)
+	for {
+		s, err := rdr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading _GeneralSuppressionNames.csv: %v", err)
+		}
+		fnln := fmt.Sprintf("%v %v", tCase(s[0]), tCase(s[1]))
+		gen[fnln]++
+	}
+	return gen, nil
+}
+
+func getLatLong(cZip, rZip string, res *Resources) (float64, float64, float64, float64, error) {
+	// Validate Record ZIP
+	recCor, OKrZip := res.cord[rZip]
+	if !OKrZip {
+		return 0, 0, 0, 0, fmt.Errorf("invalid record zip code %v", rZip)
+	}
+	// Validate Central ZIP
+	cenCor, OKcZip := res.cord[cZip]
+	if !OKcZip {
+		return 0, 0, 0, 0, fmt.Errorf("invalid central zip code %v", cZip)
+	}
+	// convert Coordinates to Float64
+	var c [4]float64
+	for i, v := range []string{cenCor[0], cenCor[1], recCor[0], recCor[1]} {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, 0, 0, 0, fmt.Errorf("converting coordinates for zip %v or %v: %v", cZip, rZip, err)
+		}
+		c[i] = f
+	}
+	return c[0], c[1], c[2], c[3], nil
+}
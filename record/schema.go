@@ -0,0 +1,165 @@
+package record
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// KnownFields are the fields every Record carries, in their internal
+// order. Config.Headers picks and orders the ones written out.
+var KnownFields = []string{"customerid", "fullname", "firstname", "mi",
+	"lastname", "address1", "address2", "addressfull", "city", "state",
+	"zip", "zip4", "scf", "phone", "hph", "bph", "cph", "email", "vin",
+	"year", "make", "model", "deldate", "date", "radius", "coordinates",
+	"vinlen", "dsfwalkseq", "crrt", "zipcrrt", "kbb", "buybackvalue",
+	"winnum", "maildnq", "blitzdnq", "drop", "purl", "ddufacility",
+	"scf3dfacility", "vendor", "expandedstate", "ethnicity", "dldyear",
+	"dldmonth", "dldday", "lsdyear", "lsdmonth", "lsdday", "misc1",
+	"misc2", "misc3", "householdid"}
+
+// fieldIndex maps each of the KnownFields to its position in a Record.
+var fieldIndex = func() map[string]int {
+	m := make(map[string]int, len(KnownFields))
+	for i, f := range KnownFields {
+		m[f] = i
+	}
+	return m
+}()
+
+// Label names the header of one output column in a Profile.
+type Label struct {
+	Field string // one of KnownFields
+	Label string // column header, Field when empty
+}
+
+// Profile is a client specific output layout: the fields to write, in
+// order, and the header label of each.
+type Profile []Label
+
+// output returns the fields and header labels selected by Config.Profile,
+// or by Headers when no profile is set, and names their source for error
+// messages. Without Headers all KnownFields are written, leaving out
+// householdid unless householding is on.
+func (cfg Config) output() (fields, labels []string, src string, err error) {
+	if cfg.Profile == "" {
+		fields = cfg.Headers
+		if len(fields) == 0 {
+			for _, f := range KnownFields {
+				if f != "householdid" || cfg.Household || cfg.HouseholdCollapse {
+					fields = append(fields, f)
+				}
+			}
+		}
+		return fields, fields, "Headers", nil
+	}
+	prof, ok := cfg.Profiles[cfg.Profile]
+	if !ok {
+		var names []string
+		for k := range cfg.Profiles {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		return nil, nil, "", fmt.Errorf("unknown output profile %q, config.json has: %v", cfg.Profile, strings.Join(names, ", "))
+	}
+	for _, l := range prof {
+		fields = append(fields, l.Field)
+		if l.Label == "" {
+			labels = append(labels, l.Field)
+		} else {
+			labels = append(labels, l.Label)
+		}
+	}
+	return fields, labels, fmt.Sprintf("profile %q", cfg.Profile), nil
+}
+
+// schema resolves the output field names into Record positions. Names are
+// matched case-insensitively and all unknown and repeated names are
+// reported together; src names where they came from.
+func schema(h []string, src string) ([]int, error) {
+	var (
+		out      []int
+		unknown  []string
+		repeated []string
+		seen     = make(map[string]bool)
+	)
+	for _, v := range h {
+		k := lCase(v)
+		i, ok := fieldIndex[k]
+		switch {
+		case !ok:
+			unknown = append(unknown, fmt.Sprintf("%q", v))
+		case seen[k]:
+			repeated = append(repeated, fmt.Sprintf("%q", v))
+		default:
+			out = append(out, i)
+		}
+		seen[k] = true
+	}
+	var errs []string
+	if len(unknown) > 0 {
+		errs = append(errs, "unknown fields "+strings.Join(unknown, ", "))
+	}
+	if len(repeated) > 0 {
+		errs = append(errs, "repeated fields "+strings.Join(repeated, ", "))
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid %v in config.json: %v", src, strings.Join(errs, "; "))
+	}
+	return out, nil
+}
+
+// Header returns the output column names.
+func (p *Processor) Header() []string {
+	return p.header
+}
+
+// OutputHeader returns the output column names for a source file with
+// header read through c: Header followed by any appended passthrough
+// columns under their source names.
+func (p *Processor) OutputHeader(header []string, c Columns) []string {
+	h := append([]string{}, p.Header()...)
+	return append(h, appended(header, c)...)
+}
+
+// OutputFields is OutputHeader with the internal field names in place of
+// the header labels, so that keys do not change with the profile.
+func (p *Processor) OutputFields(header []string, c Columns) []string {
+	f := make([]string, 0, len(p.out))
+	for _, i := range p.out {
+		f = append(f, KnownFields[i])
+	}
+	return append(f, appended(header, c)...)
+}
+
+// appended returns the source names of the passthrough columns that c
+// appends after the KnownFields.
+func appended(header []string, c Columns) []string {
+	var h []string
+	for i := len(KnownFields); ; i++ {
+		src, ok := c.src[i]
+		if !ok {
+			return h
+		}
+		h = append(h, header[src])
+	}
+}
+
+// Row returns the values of r in output column order.
+func (p *Processor) Row(r Record) []string {
+	row := make([]string, 0, len(p.out)+len(r.Fields)-len(KnownFields))
+	for _, i := range p.out {
+		row = append(row, r.Fields[i])
+	}
+	return append(row, r.Fields[len(KnownFields):]...)
+}
+
+// inOutput reports whether the field at Record position i is written out.
+func (p *Processor) inOutput(i int) bool {
+	for _, v := range p.out {
+		if v == i {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package record
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	tests := []struct {
+		h    []string
+		want []int
+		err  string
+	}{
+		{[]string{"zip", "CustomerID", "Email"}, []int{fieldIndex["zip"], fieldIndex["customerid"], fieldIndex["email"]}, ""},
+		{[]string{"zip", "bogus"}, nil, `unknown fields "bogus"`},
+		{[]string{"zip", "Zip"}, nil, `repeated fields "Zip"`},
+		{[]string{"x", "zip", "zip"}, nil, `unknown fields "x"; repeated fields "zip"`},
+	}
+	for _, tt := range tests {
+		got, err := schema(tt.h, "Headers")
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("schema(%v) error = %v, want %q", tt.h, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("schema(%v) error = %v", tt.h, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("schema(%v) = %v, want %v", tt.h, got, tt.want)
+		}
+	}
+}
+
+func TestOutput(t *testing.T) {
+	cfg := Config{
+		Headers: []string{"zip", "email"},
+		Profiles: map[string]Profile{
+			"client": {{Field: "customerid", Label: "ID"}, {Field: "zip"}},
+		},
+	}
+
+	fields, labels, _, err := cfg.output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fields, cfg.Headers) || !reflect.DeepEqual(labels, cfg.Headers) {
+		t.Errorf("Headers output = %v, %v", fields, labels)
+	}
+
+	cfg.Profile = "client"
+	fields, labels, _, err = cfg.output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"customerid", "zip"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("profile fields = %v, want %v", fields, want)
+	}
+	if want := []string{"ID", "zip"}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("profile labels = %v, want %v", labels, want)
+	}
+
+	cfg.Profile = "other"
+	if _, _, _, err := cfg.output(); err == nil || !strings.Contains(err.Error(), "client") {
+		t.Errorf("unknown profile error = %v, want the known profiles listed", err)
+	}
+}
@@ -0,0 +1,189 @@
+package record
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rssenar/monju/geo"
+)
+
+// SniffRows is how many data rows are sampled to detect columns by content.
+const SniffRows = 100
+
+// MinScore is the lowest Guess score that is used for a column.
+const MinScore = 0.8
+
+// Guess is a source column recognized by its content.
+type Guess struct {
+	Column int     // source column index
+	Field  string  // output field the content fits
+	Score  float64 // share of the sampled non-blank values that fit Field
+	Used   bool    // false when Score is too low or Field is already fed
+}
+
+// kind is a type of value that can be told apart by content. Values that
+// fit a kind go to the first of its fields not yet fed by another column.
+type kind struct {
+	fields []string
+	fits   func(v string) bool
+}
+
+// kinds are tried in order; on equal scores the earlier kind wins, so
+// years are not taken for 4 digit ZIP codes.
+var kinds = []kind{
+	{[]string{"email"}, isEmail},
+	{[]string{"vin"}, isVIN},
+	{[]string{"year"}, isYear},
+	{[]string{"zip"}, isZip},
+	{[]string{"hph", "bph", "cph"}, isPhone},
+	{[]string{"deldate", "date"}, isDate},
+	{[]string{"state"}, isState},
+	{[]string{"make"}, isMake},
+	{[]string{"address1"}, isAddress},
+}
+
+var (
+	emailRe   = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	vinRe     = regexp.MustCompile(`(?i)^[A-HJ-NPR-Z0-9]{17}$`)
+	addressRe = regexp.MustCompile(`^\d+[A-Za-z]?\s+\S+`)
+)
+
+// makes are the vehicle makes recognized in a make column.
+var makes = map[string]bool{"ACURA": true, "AUDI": true, "BMW": true,
+	"BUICK": true, "CADILLAC": true, "CHEVROLET": true, "CHEVY": true,
+	"CHRYSLER": true, "DODGE": true, "FIAT": true, "FORD": true,
+	"GENESIS": true, "GMC": true, "HONDA": true, "HYUNDAI": true,
+	"INFINITI": true, "JAGUAR": true, "JEEP": true, "KIA": true,
+	"LAND ROVER": true, "LEXUS": true, "LINCOLN": true, "MAZDA": true,
+	"MERCEDES-BENZ": true, "MERCEDES": true, "MERCURY": true, "MINI": true,
+	"MITSUBISHI": true, "NISSAN": true, "PONTIAC": true, "PORSCHE": true,
+	"RAM": true, "SATURN": true, "SCION": true, "SUBARU": true,
+	"TESLA": true, "TOYOTA": true, "VOLKSWAGEN": true, "VOLVO": true,
+	"VW": true}
+
+func isEmail(v string) bool { return emailRe.MatchString(v) }
+
+func isVIN(v string) bool { return vinRe.MatchString(v) }
+
+func isZip(v string) bool { return geo.ValZip(v) != "" }
+
+func isPhone(v string) bool { return len(ReformatPhone(v)) == 14 }
+
+func isDate(v string) bool {
+	d, _, _, _ := ParseDate(v)
+	return d != ""
+}
+
+func isState(v string) bool {
+	s := uCase(v)
+	return len(s) == 2 && geo.StateName(s) != s
+}
+
+func isMake(v string) bool { return makes[uCase(v)] }
+
+func isAddress(v string) bool { return addressRe.MatchString(v) }
+
+func isYear(v string) bool {
+	y, err := strconv.Atoi(v)
+	return err == nil && len(v) == 4 && y >= 1940 && y <= time.Now().Year()+2
+}
+
+// NumberedHeader names the columns of a file without a header row Column1
+// to ColumnN, so they can be used in a Mapping and in reports.
+func NumberedHeader(n int) []string {
+	h := make([]string, n)
+	for i := range h {
+		h[i] = fmt.Sprintf("Column%d", i+1)
+	}
+	return h
+}
+
+// Sniff works out the columns like Columns, then scores the source columns
+// that are still unassigned against the sampled rows and uses the confident
+// guesses to feed output fields that nothing else feeds. Passthrough
+// columns are taken from what is left. A ZIP column is required.
+func (p *Processor) Sniff(header []string, sample [][]string, m Mapping) (Columns, []Guess, error) {
+	c, err := p.detect(header, m)
+	if err != nil {
+		return Columns{}, nil, err
+	}
+	g := p.guess(header, sample, m, c)
+	if err := p.passthrough(header, c, m); err != nil {
+		return Columns{}, g, err
+	}
+	if _, ok := c.src[p.hdr["zip"]]; !ok {
+		return Columns{}, g, errors.New("ZIP code is a required field")
+	}
+	return c, g, nil
+}
+
+// guess scores each source column that neither m nor the aliases assigned
+// and adds the used guesses to c.
+func (p *Processor) guess(header []string, sample [][]string, m Mapping, c Columns) []Guess {
+	taken := make(map[int]bool)
+	for _, src := range c.src {
+		taken[src] = true
+	}
+	pinned, _ := m.resolve(header)
+	for src := range pinned {
+		taken[src] = true
+	}
+
+	var guesses []Guess
+	for col := range header {
+		if taken[col] {
+			continue
+		}
+		best, top := -1, 0.0
+		for k, kd := range kinds {
+			if s := score(sample, col, kd); s > top {
+				best, top = k, s
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		g := Guess{Column: col, Field: kinds[best].fields[0], Score: top}
+		if top >= MinScore {
+			for _, f := range kinds[best].fields {
+				idx, ok := p.hdr[f]
+				if !ok {
+					continue
+				}
+				if _, fed := c.src[idx]; !fed {
+					c.src[idx] = col
+					g.Field, g.Used = f, true
+					break
+				}
+			}
+		}
+		guesses = append(guesses, g)
+	}
+	return guesses
+}
+
+// score returns the share of non-blank values in column col that fit kd.
+func score(sample [][]string, col int, kd kind) float64 {
+	var n, fit int
+	for _, row := range sample {
+		if col >= len(row) {
+			continue
+		}
+		v := strings.TrimSpace(row[col])
+		if v == "" {
+			continue
+		}
+		n++
+		if kd.fits(v) {
+			fit++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return float64(fit) / float64(n)
+}
@@ -0,0 +1,256 @@
+package record
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/rssenar/monju/geo"
+	"github.com/rssenar/monju/name"
+)
+
+// Stage is one step of record processing. Run returns the updated record,
+// or drops it by calling Drop with the reason.
+type Stage interface {
+	Name() string
+	Run(r Record) (Record, error)
+}
+
+// DefaultStages is the stage order used when config.json has no Stages list.
+var DefaultStages = []string{"case", "customerid", "name", "address", "phone",
+	"vin", "zip", "radius", "dates", "state", "scf", "ethnicity", "vendor",
+	"dnm", "gensup", "filter"}
+
+// builtin holds the stages implemented by Processor, by config name.
+var builtin = map[string]func(p *Processor, r Record) (Record, error){
+	"case":       (*Processor).setCase,
+	"customerid": (*Processor).setCustomerID,
+	"name":       (*Processor).setName,
+	"address":    (*Processor).setAddress,
+	"phone":      (*Processor).setPhone,
+	"vin":        (*Processor).setVIN,
+	"zip":        (*Processor).setZip,
+	"radius":     (*Processor).setRadius,
+	"dates":      (*Processor).setDates,
+	"state":      (*Processor).setState,
+	"scf":        (*Processor).setSCF,
+	"ethnicity":  (*Processor).setEthnicity,
+	"vendor":     (*Processor).setVendor,
+	"dnm":        (*Processor).flagDNM,
+	"gensup":     (*Processor).flagGenS,
+	"filter":     (*Processor).filter,
+}
+
+// custom holds the stages added with RegisterStage.
+var custom = make(map[string]Stage)
+
+// RegisterStage makes s available to the Stages list in config.json under
+// s.Name(). It is meant to be called from init and replaces any earlier
+// custom stage of the same name; built-in names cannot be reused.
+func RegisterStage(s Stage) error {
+	if _, ok := builtin[s.Name()]; ok {
+		return fmt.Errorf("stage %v is built in", s.Name())
+	}
+	custom[s.Name()] = s
+	return nil
+}
+
+// stageFunc binds a built-in stage to its Processor.
+type stageFunc struct {
+	name string
+	p    *Processor
+	run  func(p *Processor, r Record) (Record, error)
+}
+
+func (s stageFunc) Name() string {
+	return s.name
+}
+
+func (s stageFunc) Run(r Record) (Record, error) {
+	return s.run(s.p, r)
+}
+
+// stage looks up a stage by its config name.
+func (p *Processor) stage(n string) (Stage, error) {
+	if run, ok := builtin[n]; ok {
+		return stageFunc{name: n, p: p, run: run}, nil
+	}
+	if s, ok := custom[n]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("unknown stage %q in config.json", n)
+}
+
+func (p *Processor) setCase(rec Record) (Record, error) {
+	hdr := p.hdr
+	for i, v := range rec.Fields {
+		if rec.pass[i] {
+			// Passthrough columns are written as read
+			continue
+		}
+		switch i {
+		case hdr["state"], hdr["vin"]:
+			rec.Fields[i] = uCase(v)
+		case hdr["email"]:
+			rec.Fields[i] = lCase(v)
+		case hdr["hph"], hdr["bph"], hdr["cph"]:
+			rec.Fields[i] = ReformatPhone(v)
+		case hdr["address1"], hdr["address2"]:
+			rec.Fields[i] = StdAddress(v)
+		default:
+			rec.Fields[i] = tCase(v)
+		}
+	}
+	return rec, nil
+}
+
+func (p *Processor) setCustomerID(rec Record) (Record, error) {
+	hdr := p.hdr
+	switch uCase(p.cfg.Source) {
+	case "D":
+		rec.Fields[hdr["customerid"]] = fmt.Sprintf("D%d", rec.Counter+100000)
+	case "P":
+		rec.Fields[hdr["customerid"]] = fmt.Sprintf("P%d", rec.Counter+500000)
+	default:
+		rec.Fields[hdr["customerid"]] = fmt.Sprintf("%06d", rec.Counter)
+	}
+	return rec, nil
+}
+
+func (p *Processor) setName(rec Record) (Record, error) {
+	hdr := p.hdr
+	// Parse FullName if FirstName & LastName == ""
+	if rec.Fields[hdr["fullname"]] != "" && rec.Fields[hdr["firstname"]] == "" && rec.Fields[hdr["lastname"]] == "" {
+		rec.Fields[hdr["firstname"]], rec.Fields[hdr["mi"]], rec.Fields[hdr["lastname"]] = name.Parse(rec.Fields[hdr["fullname"]])
+	}
+
+	// Combine FirstName + LastName to FullName
+	if rec.Fields[hdr["fullname"]] == "" {
+		rec.Fields[hdr["fullname"]] = fmt.Sprintf("%v %v", rec.Fields[hdr["firstname"]], rec.Fields[hdr["lastname"]])
+	}
+	return rec, nil
+}
+
+func (p *Processor) setAddress(rec Record) (Record, error) {
+	hdr := p.hdr
+	// Combine address1 + Address2 to AddressFull
+	rec.Fields[hdr["addressfull"]] = fmt.Sprintf("%v %v", rec.Fields[hdr["address1"]], rec.Fields[hdr["address2"]])
+	return rec, nil
+}
+
+func (p *Processor) setPhone(rec Record) (Record, error) {
+	hdr := p.hdr
+	// Set Phone field based on availability of hph, bph & cph
+	switch {
+	case rec.Fields[hdr["hph"]] != "":
+		rec.Fields[hdr["phone"]] = rec.Fields[hdr["hph"]]
+	case rec.Fields[hdr["bph"]] != "":
+		rec.Fields[hdr["phone"]] = rec.Fields[hdr["bph"]]
+	case rec.Fields[hdr["cph"]] != "":
+		rec.Fields[hdr["phone"]] = rec.Fields[hdr["cph"]]
+	}
+	return rec, nil
+}
+
+func (p *Processor) setVIN(rec Record) (Record, error) {
+	hdr := p.hdr
+	rec.Fields[hdr["vinlen"]] = fmt.Sprint(len(rec.Fields[hdr["vin"]]))
+	return rec, nil
+}
+
+func (p *Processor) setZip(rec Record) (Record, error) {
+	hdr := p.hdr
+	// Set ZipCrrt
+	rec.Fields[hdr["zipcrrt"]] = fmt.Sprintf("%v%v", rec.Fields[hdr["zip"]], rec.Fields[hdr["crrt"]])
+	// Standardize Zipcode
+	rec.Fields[hdr["zip"]] = geo.ValZip(rec.Fields[hdr["zip"]])
+	return rec, nil
+}
+
+func (p *Processor) setRadius(rec Record) (Record, error) {
+	hdr := p.hdr
+	// Validate record Zipcode
+	_, okRzip := p.res.cord[rec.Fields[hdr["zip"]]]
+	if !okRzip {
+		log.Printf("Invalid Zip Code on row %v, zip code %v (%v, %v) ", rec.Counter, rec.Fields[hdr["zip"]], rec.Fields[hdr["city"]], rec.Fields[hdr["state"]])
+		return rec, nil
+	}
+	// Set Radius(miles) based on Central Zip and Row Zip
+	clat1, clon2, rlat1, rlon2, err := getLatLong(geo.ValZip(strconv.Itoa(p.cfg.CentZip)), rec.Fields[hdr["zip"]], p.res)
+	if err != nil {
+		return rec, fmt.Errorf("row %v: %v", rec.Counter, err)
+	}
+	rec.Fields[hdr["radius"]] = fmt.Sprintf("%.2f", geo.Distance(clat1, clon2, rlat1, rlon2))
+	// Set Coordinte value
+	rec.Fields[hdr["coordinates"]] = fmt.Sprintf("%v,%v", rlat1, rlon2)
+	return rec, nil
+}
+
+func (p *Processor) setDates(rec Record) (Record, error) {
+	hdr := p.hdr
+	// Set DelDate, Date, Dld_Year, Dld_Month, Dld_Day
+	rec.Fields[hdr["deldate"]], rec.Fields[hdr["dldyear"]], rec.Fields[hdr["dldmonth"]], rec.Fields[hdr["dldday"]] = ParseDate(rec.Fields[hdr["deldate"]])
+	rec.Fields[hdr["date"]], rec.Fields[hdr["lsdyear"]], rec.Fields[hdr["lsdmonth"]], rec.Fields[hdr["lsdday"]] = ParseDate(rec.Fields[hdr["date"]])
+	return rec, nil
+}
+
+func (p *Processor) setState(rec Record) (Record, error) {
+	hdr := p.hdr
+	rec.Fields[hdr["expandedstate"]] = geo.StateName(rec.Fields[hdr["state"]])
+	return rec, nil
+}
+
+func (p *Processor) setSCF(rec Record) (Record, error) {
+	hdr := p.hdr
+	// Set SCF value
+	rec.Fields[hdr["scf"]] = geo.SCF(rec.Fields[hdr["zip"]])
+
+	// Set DDU Faculity
+	if ddufac, ok := p.res.dduFac[rec.Fields[hdr["zip"]]]; ok {
+		rec.Fields[hdr["ddufacility"]] = ddufac
+	}
+
+	// Set SCF Faculity
+	if scffac, ok := p.res.scfFac[rec.Fields[hdr["scf"]]]; ok {
+		rec.Fields[hdr["scf3dfacility"]] = scffac
+	}
+	return rec, nil
+}
+
+func (p *Processor) setEthnicity(rec Record) (Record, error) {
+	hdr := p.hdr
+	if _, ok := p.res.hist[rec.Fields[hdr["lastname"]]]; ok {
+		rec.Fields[hdr["ethnicity"]] = "Hisp"
+	}
+	return rec, nil
+}
+
+func (p *Processor) setVendor(rec Record) (Record, error) {
+	hdr := p.hdr
+	rec.Fields[hdr["vendor"]] = p.cfg.Vendor
+	return rec, nil
+}
+
+func (p *Processor) flagDNM(rec Record) (Record, error) {
+	hdr := p.hdr
+	if reason := p.matchDNM(rec); reason != "" {
+		// The first suppression found is kept, with its maildnq flag
+		if rec.Suppress == "" {
+			rec.Fields[hdr["maildnq"]] = "DNM"
+			rec.Suppress = reason
+		}
+	}
+	return rec, nil
+}
+
+func (p *Processor) flagGenS(rec Record) (Record, error) {
+	hdr := p.hdr
+	if reason := p.matchGenS(rec); reason != "" {
+		rec.Fields[hdr["blitzdnq"]] = "GenS"
+		if rec.Suppress == "" {
+			rec.Fields[hdr["maildnq"]] = "GenS"
+			rec.Suppress = reason
+		}
+	}
+	return rec, nil
+}
@@ -0,0 +1,135 @@
+package record
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rssenar/monju/geo"
+)
+
+// dnmList holds the Do-Not-Mail keys, one set per kind of match.
+type dnmList struct {
+	name   map[string]int
+	adrZip map[string]int
+	email  map[string]int
+	phone  map[string]int
+}
+
+// dnmHeaders are the DoNotMail.csv header names, matched against the whole
+// column name so that names and addresses in a header-less file are not
+// taken for a header.
+var dnmHeaders = []struct {
+	key string
+	re  *regexp.Regexp
+}{
+	{"name", regexp.MustCompile(`(?i)^(full.?)?name$`)},
+	{"firstname", regexp.MustCompile(`(?i)^first.?name$`)},
+	{"lastname", regexp.MustCompile(`(?i)^last.?name$`)},
+	{"address", regexp.MustCompile(`(?i)^addr(ess)?.?1?$`)},
+	{"zip", regexp.MustCompile(`(?i)^zip(.?code)?$`)},
+	{"email", regexp.MustCompile(`(?i)^e.?mail$`)},
+	{"phone", regexp.MustCompile(`(?i)^((home|cell|work).?)?phone$|^[hbc]ph$`)},
+}
+
+// dataLike matches values that only occur in data rows.
+var dataLike = regexp.MustCompile(`[0-9@]`)
+
+// dnmCols maps a DoNotMail.csv header row to column positions. It reports
+// false when the row does not look like a header, either because no column
+// name is recognized or because some value looks like data.
+func dnmCols(r []string) (map[string]int, bool) {
+	c := make(map[string]int)
+	for i, v := range r {
+		v = strings.TrimSpace(v)
+		if dataLike.MatchString(v) {
+			return nil, false
+		}
+		for _, h := range dnmHeaders {
+			if h.re.MatchString(v) {
+				c[h.key] = i
+				break
+			}
+		}
+	}
+	return c, len(c) > 0
+}
+
+// add registers the keys found in a DoNotMail.csv row.
+func (d dnmList) add(r []string, col map[string]int) {
+	get := func(k string) string {
+		if i, ok := col[k]; ok && i < len(r) {
+			return r[i]
+		}
+		return ""
+	}
+	nm := tCase(get("name"))
+	if nm == "" {
+		nm = tCase(strings.TrimSpace(fmt.Sprintf("%v %v", get("firstname"), get("lastname"))))
+	}
+	if nm != "" {
+		d.name[nm]++
+	}
+	if adr, zip := StdAddress(get("address")), geo.ValZip(strings.TrimSpace(get("zip"))); adr != "" && zip != "" {
+		d.adrZip[fmt.Sprintf("%v %v", adr, zip)]++
+	}
+	if email := lCase(get("email")); email != "" {
+		d.email[email]++
+	}
+	if ph := digits(get("phone")); ph != "" {
+		d.phone[ph]++
+	}
+}
+
+// matchDNM returns the reason a processed record matches the Do-Not-Mail
+// list, or "" when it does not.
+func (p *Processor) matchDNM(r Record) string {
+	hdr := p.hdr
+	rec := r.Fields
+	fnln := tCase(fmt.Sprintf("%v %v", rec[hdr["firstname"]], rec[hdr["lastname"]]))
+	if _, ok := p.res.dnm.name[tCase(rec[hdr["fullname"]])]; ok {
+		return "DNM name"
+	}
+	if _, ok := p.res.dnm.name[fnln]; ok && fnln != "" {
+		return "DNM name"
+	}
+	if rec[hdr["address1"]] != "" && rec[hdr["zip"]] != "" {
+		if _, ok := p.res.dnm.adrZip[fmt.Sprintf("%v %v", rec[hdr["address1"]], rec[hdr["zip"]])]; ok {
+			return "DNM address"
+		}
+	}
+	if _, ok := p.res.dnm.email[rec[hdr["email"]]]; ok && rec[hdr["email"]] != "" {
+		return "DNM email"
+	}
+	for _, k := range []string{"hph", "bph", "cph"} {
+		if ph := digits(rec[hdr[k]]); ph != "" {
+			if _, ok := p.res.dnm.phone[ph]; ok {
+				return "DNM phone"
+			}
+		}
+	}
+	return ""
+}
+
+// matchGenS returns the reason a processed record matches the General
+// Suppression address+zip or name lists, or "" when it does not.
+func (p *Processor) matchGenS(r Record) string {
+	hdr := p.hdr
+	rec := r.Fields
+	if rec[hdr["address1"]] != "" && rec[hdr["zip"]] != "" {
+		if _, ok := p.res.genS[fmt.Sprintf("%v %v", rec[hdr["address1"]], rec[hdr["zip"]])]; ok {
+			return "GenS address"
+		}
+	}
+	if rec[hdr["firstname"]] != "" && rec[hdr["lastname"]] != "" {
+		if _, ok := p.res.genSNm[fmt.Sprintf("%v %v", rec[hdr["firstname"]], rec[hdr["lastname"]])]; ok {
+			return "GenS name"
+		}
+	}
+	return ""
+}
+
+// digits strips everything but 0-9 from a phone number.
+func digits(p string) string {
+	return regexp.MustCompile(`[^0-9]`).ReplaceAllString(p, "")
+}
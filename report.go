@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rssenar/monju/record"
+)
+
+// printSuppressed reports the per-run suppression counts by reason.
+func printSuppressed(sup map[string]int, mode string) {
+	var total int
+	var reasons []string
+	for k, v := range sup {
+		total += v
+		reasons = append(reasons, fmt.Sprintf("%v: %v", k, v))
+	}
+	if total == 0 {
+		return
+	}
+	sort.Strings(reasons)
+	action := "Flagged"
+	switch mode {
+	case "drop":
+		action = "Dropped"
+	case "split":
+		action = "Split out"
+	}
+	fmt.Fprintf(status, "%v %v suppressed records (%v)\n", action, total, strings.Join(reasons, ", "))
+}
+
+// printRejected reports how many records each filter rule removed.
+func printRejected(rej map[string]int) {
+	var total int
+	var rules []string
+	for k, v := range rej {
+		total += v
+		rules = append(rules, fmt.Sprintf("%v: %v", k, v))
+	}
+	if total == 0 {
+		return
+	}
+	sort.Strings(rules)
+	fmt.Fprintf(status, "Removed %v records (%v)\n", total, strings.Join(rules, ", "))
+}
+
+// printColumns reports the source columns that were not recognized and the
+// output fields left empty because no source column matched them.
+func printColumns(unmatched, empty []string) {
+	if len(unmatched) > 0 {
+		fmt.Fprintf(status, "Unmatched source columns: %v\n", strings.Join(unmatched, ", "))
+	}
+	if len(empty) > 0 {
+		fmt.Fprintf(status, "No source column for: %v\n", strings.Join(empty, ", "))
+	}
+}
+
+// printTruncated reports the values that were cut to fit a fixed-width
+// field of file: a count by field, then each value with its source row.
+func printTruncated(file string, t []truncation) {
+	if len(t) == 0 {
+		return
+	}
+	n := make(map[string]int)
+	for _, v := range t {
+		n[v.field]++
+	}
+	var fields []string
+	for k, v := range n {
+		fields = append(fields, fmt.Sprintf("%v: %v", k, v))
+	}
+	sort.Strings(fields)
+	fmt.Fprintf(status, "Truncated %v values in %v (%v)\n", len(t), file, strings.Join(fields, ", "))
+	for _, v := range t {
+		fmt.Fprintf(status, "  row %v, %v: %q\n", v.row, v.field, v.value)
+	}
+}
+
+// printGuesses reports the columns detected by content with their scores.
+func printGuesses(header []string, g []record.Guess) {
+	for _, v := range g {
+		note := ""
+		if !v.Used {
+			note = " (not used)"
+		}
+		fmt.Fprintf(status, "Detected %v as %v, %.0f%% confidence%v\n", header[v.Column], v.Field, v.Score*100, note)
+	}
+}
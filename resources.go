@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rssenar/monju/record"
+)
+
+// resourceEnv names the environment variable that points at the resource
+// directory when the -resources flag is not given.
+const resourceEnv = "MONJU_RESOURCES"
+
+// resourceDirs returns the directories searched for resource files, in
+// order: $XDG_DATA_HOME/monju, each $XDG_DATA_DIRS entry, then the legacy
+// ~/Dropbox/Resource location.
+func resourceDirs() []string {
+	var dirs []string
+	home, _ := os.UserHomeDir()
+
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" && home != "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	if dataHome != "" {
+		dirs = append(dirs, filepath.Join(dataHome, "monju"))
+	}
+
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	for _, d := range filepath.SplitList(dataDirs) {
+		if d != "" {
+			dirs = append(dirs, filepath.Join(d, "monju"))
+		}
+	}
+
+	if home != "" {
+		dirs = append(dirs, filepath.Join(home, "Dropbox", "Resource"))
+	}
+	return dirs
+}
+
+// findResources resolves the resource directory. An explicit directory
+// (from -resources, else $MONJU_RESOURCES) is used as is and must hold
+// every file in record.ResourceFiles; otherwise the first directory from
+// resourceDirs that holds them all is chosen, so an empty or partial one
+// does not hide a complete one further down.
+func findResources(flagDir string) (string, error) {
+	dir := flagDir
+	if dir == "" {
+		dir = os.Getenv(resourceEnv)
+	}
+	if dir != "" {
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			return "", fmt.Errorf("resource directory %v does not exist", dir)
+		}
+		if missing := missingResources(dir); len(missing) > 0 {
+			return "", fmt.Errorf("resource directory %v is missing: %v", dir, strings.Join(missing, ", "))
+		}
+		return dir, nil
+	}
+
+	searched := resourceDirs()
+	var partial []string
+	for _, d := range searched {
+		if fi, err := os.Stat(d); err != nil || !fi.IsDir() {
+			continue
+		}
+		missing := missingResources(d)
+		if len(missing) == 0 {
+			return d, nil
+		}
+		partial = append(partial, fmt.Sprintf("%v is missing: %v", d, strings.Join(missing, ", ")))
+	}
+	if len(partial) > 0 {
+		return "", fmt.Errorf("no complete resource directory found, set -resources or $%v (%v)",
+			resourceEnv, strings.Join(partial, "; "))
+	}
+	return "", fmt.Errorf("no resource directory found, set -resources or $%v (searched %v)",
+		resourceEnv, strings.Join(searched, ", "))
+}
+
+// missingResources returns the files of record.ResourceFiles not in dir.
+func missingResources(dir string) []string {
+	var missing []string
+	for _, f := range record.ResourceFiles {
+		if _, err := os.Stat(filepath.Join(dir, f)); err != nil {
+			missing = append(missing, f)
+		}
+	}
+	return missing
+}
+
+// loadProcessor reads the config and resource files once, reporting how
+// long each took, and builds the Processor shared by all input files. A
+// non-empty profile overrides the output profile set in config.json.
+func loadProcessor(rescPath, profile string) (*record.Processor, error) {
+	t := time.Now()
+	cfg, err := record.LoadConfig(rescPath)
+	if err != nil {
+		return nil, err
+	}
+	if profile != "" {
+		cfg.Profile = profile
+	}
+	fmt.Fprintf(status, "Loaded config.json in %v\n", time.Since(t))
+	res, err := record.LoadResources(rescPath, func(file string, d time.Duration) {
+		fmt.Fprintf(status, "Loaded %v in %v\n", file, d)
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(status, "Resources loaded from %v in %v\n", rescPath, time.Since(t))
+	return record.New(cfg, res)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// formats maps each output format to the extension of the files it writes.
+var formats = map[string]string{
+	"csv":   ".csv",
+	"tsv":   ".tsv",
+	"pipe":  ".psv",
+	"jsonl": ".jsonl",
+	"fixed": ".txt",
+	"xlsx":  ".xlsx",
+}
+
+// formatFor picks the output format from the extension of path, ignoring
+// any .gz, falling back to csv.
+func formatFor(path string) string {
+	ext := strings.ToLower(filepath.Ext(stripGz(path)))
+	if ext == ".ndjson" {
+		return "jsonl"
+	}
+	for f, e := range formats {
+		if e == ext {
+			return f
+		}
+	}
+	return "csv"
+}
+
+// formatNames lists the output formats for usage and error messages.
+func formatNames() string {
+	var n []string
+	for f := range formats {
+		n = append(n, f)
+	}
+	sort.Strings(n)
+	return strings.Join(n, ", ")
+}
+
+// recordWriter writes output rows in one file format.
+type recordWriter interface {
+	Write(row []string) error
+	Flush() error
+}
+
+// newWriter returns a recordWriter for format on w. header names the
+// columns; the delimited formats write it out first. keys are the field
+// names that the jsonl and fixed formats use instead and lay is only used
+// by the fixed format.
+func newWriter(w io.Writer, format string, lay layout, header, keys []string) (recordWriter, error) {
+	switch format {
+	case "fixed":
+		return newFixedWriter(w, lay, keys)
+	case "xlsx":
+		return newXLSXWriter(w, header)
+	case "csv", "tsv", "pipe":
+		dw := delimWriter{csv.NewWriter(w)}
+		switch format {
+		case "tsv":
+			dw.w.Comma = '\t'
+		case "pipe":
+			dw.w.Comma = '|'
+		}
+		return dw, dw.Write(header)
+	case "jsonl":
+		return newJSONLWriter(w, keys), nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+// delimWriter writes CSV, tab or pipe separated rows.
+type delimWriter struct {
+	w *csv.Writer
+}
+
+func (d delimWriter) Write(row []string) error {
+	return d.w.Write(row)
+}
+
+func (d delimWriter) Flush() error {
+	d.w.Flush()
+	return d.w.Error()
+}
+
+// jsonlWriter writes one JSON object per row, keyed by field name in
+// column order. HTML characters are written as is.
+type jsonlWriter struct {
+	w    *bufio.Writer
+	keys []string
+	buf  bytes.Buffer
+	enc  *json.Encoder // encodes into buf
+}
+
+func newJSONLWriter(w io.Writer, keys []string) *jsonlWriter {
+	j := &jsonlWriter{w: bufio.NewWriter(w), keys: keys}
+	j.enc = json.NewEncoder(&j.buf)
+	j.enc.SetEscapeHTML(false)
+	return j
+}
+
+func (j *jsonlWriter) Write(row []string) error {
+	j.w.WriteByte('{')
+	for i, v := range row {
+		if i > 0 {
+			j.w.WriteByte(',')
+		}
+		j.str(j.keys[i])
+		j.w.WriteByte(':')
+		j.str(v)
+	}
+	_, err := j.w.WriteString("}\n")
+	return err
+}
+
+// str writes s as a JSON string, without the newline Encode adds.
+func (j *jsonlWriter) str(s string) {
+	j.buf.Reset()
+	j.enc.Encode(s)
+	j.w.Write(bytes.TrimSuffix(j.buf.Bytes(), []byte("\n")))
+}
+
+func (j *jsonlWriter) Flush() error {
+	return j.w.Flush()
+}
+
+// xlsxWriter streams rows into a single worksheet; the workbook is only
+// written out on Flush. Values are stored as text so ZIP codes keep their
+// leading zeros.
+type xlsxWriter struct {
+	w   io.Writer
+	f   *excelize.File
+	sw  *excelize.StreamWriter
+	row int
+}
+
+func newXLSXWriter(w io.Writer, header []string) (*xlsxWriter, error) {
+	f := excelize.NewFile()
+	sw, err := f.NewStreamWriter(f.GetSheetName(0))
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxWriter{w: w, f: f, sw: sw}
+	return x, x.Write(header)
+}
+
+func (x *xlsxWriter) Write(row []string) error {
+	x.row++
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	v := make([]interface{}, len(row))
+	for i, s := range row {
+		v[i] = s
+	}
+	return x.sw.SetRow(cell, v)
+}
+
+func (x *xlsxWriter) Flush() error {
+	defer x.f.Close()
+	if err := x.sw.Flush(); err != nil {
+		return err
+	}
+	return x.f.Write(x.w)
+}